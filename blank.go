@@ -0,0 +1,58 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import "context"
+
+// Blank is the value of an erased byte.
+const Blank byte = 0xff
+
+// BlankCheck reads the entire device and returns the ranges that contain a
+// value other than blank, which is typically Blank. An empty slice is
+// returned if the device is blank.
+func (d *Device) BlankCheck(blank byte) ([]Range, error) {
+	return d.BlankCheckContext(context.Background(), blank)
+}
+
+// BlankCheckContext is like BlankCheck, but stops between packets once the
+// given context is done.
+func (d *Device) BlankCheckContext(ctx context.Context, blank byte) ([]Range, error) {
+	data := make([]byte, d.Size())
+	if err := d.ReadExtendedContext(ctx, 0, data); err != nil {
+		return nil, err
+	}
+
+	var ranges []Range
+	for i, b := range data {
+		if b == blank {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End() == i {
+			ranges[n-1].Len++
+		} else {
+			ranges = append(ranges, Range{uint32(i), 1})
+		}
+	}
+	return ranges, nil
+}
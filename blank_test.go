@@ -0,0 +1,65 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestBlankCheck(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C16"))
+
+	ranges, err := d.BlankCheck(eeprom.Blank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 0 {
+		t.Fatalf("expected blank device; got %v", ranges)
+	}
+
+	m := s.Memory()
+	m[0x10], m[0x11], m[0x7ff] = 0x00, 0x01, 0x02
+	ranges, err = d.BlankCheck(eeprom.Blank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []eeprom.Range{{0x10, 2}, {0x7ff, 1}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("expected %v; got %v", want, ranges)
+	}
+
+	ranges, err = d.BlankCheck(0x00)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []eeprom.Range{{0x00, 0x10}, {0x11, 0x7ef}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("expected %v; got %v", want, ranges)
+	}
+}
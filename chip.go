@@ -0,0 +1,76 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"strings"
+	"time"
+)
+
+// Chip describes the characteristics of an EEPROM. A Device may be bound to a
+// Chip using SetChip, in which case its constraints are enforced by the
+// Device rather than the caller.
+type Chip struct {
+	Name       string
+	Capacity   int           // number of bytes
+	PageSize   int           // number of bytes per page; zero if unsupported
+	PageAlign  int           // required alignment of page writes, in bytes
+	WriteCycle time.Duration // maximum byte or page write cycle time
+	ChipErase  bool          // supports the JEDEC software chip erase sequence
+	SDP        bool          // supports Software Data Protection
+	SDPAddr    [2]uint16     // addresses of SDP command cycles
+}
+
+// Chip Erase is issued by the programmer as the six cycle JEDEC software chip
+// erase sequence, which is recognized by the same parts that support Software
+// Data Protection. The AT28C16, AT28C17 and AT28C64 instead provide a chip
+// clear that requires 12V on OE, which the programmer cannot supply.
+var chips = []Chip{
+	{"AT28C16", 2 << 10, 0, 0, 1 * time.Millisecond, false, false, [2]uint16{}},
+	{"AT28C17", 2 << 10, 0, 0, 1 * time.Millisecond, false, false, [2]uint16{}},
+	{"AT28C64", 8 << 10, 0, 0, 1 * time.Millisecond, false, false, [2]uint16{}},
+	{"AT28C64B", 8 << 10, 64, 64, 10 * time.Millisecond, true, true, [2]uint16{0x1555, 0x0aaa}},
+	{"AT28C256", 32 << 10, 64, 64, 10 * time.Millisecond, true, true, [2]uint16{0x5555, 0x2aaa}},
+	{"X28C64", 8 << 10, 64, 64, 5 * time.Millisecond, true, true, [2]uint16{0x1555, 0x0aaa}},
+	{"X28C256", 32 << 10, 64, 64, 5 * time.Millisecond, true, true, [2]uint16{0x5555, 0x2aaa}},
+	{"AT28C010", 128 << 10, 128, 128, 10 * time.Millisecond, true, true, [2]uint16{0x5555, 0x2aaa}},
+	{"AT28C040", 512 << 10, 256, 256, 10 * time.Millisecond, true, true, [2]uint16{0x5555, 0x2aaa}},
+}
+
+// Chips returns the chips known to the package.
+func Chips() []Chip {
+	return append([]Chip(nil), chips...)
+}
+
+// LookupChip returns the known chip with the given name. Names are matched
+// without regard to case. Nil is returned if the chip is not known.
+func LookupChip(name string) *Chip {
+	for i := range chips {
+		if strings.EqualFold(chips[i].Name, name) {
+			c := chips[i]
+			return &c
+		}
+	}
+	return nil
+}
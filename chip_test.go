@@ -0,0 +1,104 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestLookupChip(t *testing.T) {
+	if c := eeprom.LookupChip("at28c256"); c == nil || c.Name != "AT28C256" {
+		t.Fatalf("expected AT28C256; got %v", c)
+	}
+	if c := eeprom.LookupChip("28C999"); c != nil {
+		t.Fatalf("expected nil; got %v", c)
+	}
+}
+
+func TestChipCapacity(t *testing.T) {
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	d.SetChip(eeprom.LookupChip("AT28C64"))
+
+	if size := d.Size(); size != 8<<10 {
+		t.Fatalf("expected size %d; got %d", 8<<10, size)
+	}
+	if err := d.WriteBytes(0, make([]byte, 8<<10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteBytes(1, make([]byte, 8<<10)); err == nil {
+		t.Fatal("expected error writing beyond capacity")
+	}
+}
+
+func TestChipPages(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C256"))
+	s.SetFaults(eeprom.Faults{DropPage: 2})
+
+	// Every other 64-byte page is dropped, which is only observable if
+	// the page size of the chip is used.
+	if err := d.WritePages(0, make([]byte, 256)); err != nil {
+		t.Fatal(err)
+	}
+	mem := s.Memory()
+	if mem[63] != 0x00 || mem[64] != 0xff || mem[128] != 0x00 {
+		t.Fatalf("unexpected page contents % x", mem[62:66])
+	}
+	if err := d.WritePages(32, make([]byte, 64)); err == nil {
+		t.Fatal("expected error for unaligned page write")
+	}
+}
+
+func TestChipErase(t *testing.T) {
+	tests := []struct {
+		chip string
+		size int // number of bytes erased
+	}{
+		// Chip Erase erases the entire simulated memory array,
+		// whereas writing 0xff only erases the capacity of the chip.
+		{"AT28C64", 8 << 10},
+		{"AT28C64B", eeprom.MaxBytes},
+	}
+	for _, test := range tests {
+		s := eeprom.NewSimulator()
+		d := eeprom.NewDevice(s)
+		d.SetChip(eeprom.LookupChip(test.chip))
+
+		mem := s.Memory()
+		for i := range mem {
+			mem[i] = 0
+		}
+		if err := d.Erase(); err != nil {
+			t.Fatalf("%s: %v", test.chip, err)
+		}
+		for i, b := range mem {
+			if want := i < test.size; want != (b == 0xff) {
+				t.Fatalf("%s: mem[%d]: unexpected %#x", test.chip, i, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var blankValue int
+
+func init() {
+	cmd := &command{
+		name: "blank",
+		exec: blank,
+		help: `usage: eeprom blank [-chip name] [-value n]
+
+The blank command reads the entire device and checks that it is blank. If the
+device is not blank, the ranges that are not blank are printed and the command
+exits with a non-zero status. If standard error is a terminal, progress is
+displayed while reading.
+
+The flags are:
+
+    -chip name
+		chip to use, which determines capacity and page size; see
+		"eeprom chips" for a list of supported chips.
+    -value n
+		value of a blank byte; by default this is 0xff.
+`,
+	}
+	cmd.flag.IntVar(&blankValue, "value", 0xff, "")
+	addChipFlag(cmd)
+	addCommand(cmd)
+}
+
+func blank(...string) error {
+	if blankValue < 0 || blankValue > 0xff {
+		return errUsage
+	}
+
+	d, err := openDevice()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	done := newProgressBar("blank").attach(d)
+	ranges, err := d.BlankCheck(byte(blankValue))
+	done()
+	if err != nil {
+		d.Reset()
+		return err
+	}
+	for _, r := range ranges {
+		fmt.Printf("%v (%d bytes)\n", r, r.Len)
+	}
+	if len(ranges) > 0 {
+		return errors.New("device is not blank")
+	}
+	return nil
+}
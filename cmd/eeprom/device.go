@@ -24,26 +24,303 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sstallion/go-eeprom"
 )
 
-var deviceID string
+var deviceID, matchSpec, simFile, simFaults, chipName string
+
+var recordFile, replayFile string
+
+var (
+	retries int
+	backoff time.Duration
+)
+
+var transferTimeout, byteTimeout, statusTimeout, settleTime time.Duration
 
 func init() {
 	flag.StringVar(&deviceID, "id", "", "")
+	flag.StringVar(&matchSpec, "match", os.Getenv("EEPROM_MATCH"), "")
+	flag.IntVar(&retries, "retries", 0, "")
+	flag.DurationVar(&backoff, "backoff", time.Second, "")
+	flag.DurationVar(&transferTimeout, "timeout", 2500*time.Millisecond, "")
+	flag.DurationVar(&byteTimeout, "bytetimeout", 0, "")
+	flag.DurationVar(&statusTimeout, "statustimeout", 0, "")
+	flag.DurationVar(&settleTime, "settle", 500*time.Millisecond, "")
+	flag.StringVar(&simFile, "sim", "", "")
+	flag.StringVar(&simFaults, "fault", "", "")
+	flag.StringVar(&recordFile, "record", "", "")
+	flag.StringVar(&replayFile, "replay", "", "")
+}
+
+// parseMatches parses a comma-separated list of vid:pid[:interface[:endpoint]]
+// specifications identifying supported devices. Numbers are hexadecimal.
+func parseMatches(spec string) ([]eeprom.Match, error) {
+	var matches []eeprom.Match
+
+	for _, field := range strings.Split(spec, ",") {
+		parts := strings.Split(field, ":")
+		if len(parts) < 2 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid match: %s", field)
+		}
+		var v [4]uint64
+		for i, part := range parts {
+			var err error
+			if v[i], err = strconv.ParseUint(part, 16, 16); err != nil {
+				return nil, fmt.Errorf("invalid match: %s", field)
+			}
+		}
+		matches = append(matches, eeprom.Match{
+			Vendor:    uint16(v[0]),
+			Product:   uint16(v[1]),
+			Interface: int(v[2]),
+			Endpoint:  int(v[3]),
+		})
+	}
+	return matches, nil
+}
+
+// parseFaults parses a comma-separated list of name=value pairs describing
+// faults to inject into a simulated device.
+func parseFaults(spec string) (eeprom.Faults, error) {
+	var f eeprom.Faults
+
+	for _, field := range strings.Split(spec, ",") {
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			return f, fmt.Errorf("invalid fault: %s", field)
+		}
+		name := field[:i]
+		v, err := strconv.ParseUint(field[i+1:], 0, 16)
+		if err != nil {
+			return f, fmt.Errorf("invalid fault: %s", field)
+		}
+		switch name {
+		case "short":
+			f.MaxTransfer = int(v)
+		case "timeout":
+			f.Err, f.ErrAfter = eeprom.ErrTimeout, int(v)
+		case "fail":
+			f.Err, f.ErrAfter = errors.New("simulated failure"), int(v)
+		case "status":
+			f.StatusMask = uint16(v)
+		case "stuckhigh":
+			f.StuckHigh = byte(v)
+		case "stucklow":
+			f.StuckLow = byte(v)
+		case "drop":
+			f.DropPage = int(v)
+		case "stop":
+			f.StopAfter = int(v)
+		default:
+			return f, fmt.Errorf("invalid fault: %s", field)
+		}
+	}
+	return f, nil
+}
+
+// simTransport persists the contents of a simulated device when closed.
+type simTransport struct {
+	*eeprom.Simulator
+	name string
+}
+
+func (t *simTransport) ID() string { return t.name }
+
+func (t *simTransport) Close() error {
+	return ioutil.WriteFile(t.name, t.Memory(), 0666)
+}
+
+// openSimulator returns a simulated device backed by the named file. Extended
+// addressing is supported if the file or the chip given by -chip is larger
+// than eeprom.MaxBytes.
+func openSimulator(name string) (*eeprom.Device, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	size := len(data)
+	if chip := eeprom.LookupChip(chipName); chip != nil && chip.Capacity > size {
+		size = chip.Capacity
+	}
+	s := eeprom.NewSimulator()
+	if size > eeprom.MaxBytes {
+		s = eeprom.NewExtendedSimulator(size)
+	}
+	copy(s.Memory(), data)
+
+	if simFaults != "" {
+		f, err := parseFaults(simFaults)
+		if err != nil {
+			return nil, err
+		}
+		s.SetFaults(f)
+	}
+	return eeprom.NewDevice(&simTransport{s, name}), nil
+}
+
+// addChipFlag adds the -chip flag to the given command.
+func addChipFlag(cmd *command) {
+	cmd.flag.StringVar(&chipName, "chip", "", "")
 }
 
 func openDevice() (*eeprom.Device, error) {
+	chip, err := lookupChip()
+	if err != nil {
+		return nil, err
+	}
+	d, err := findDevice()
+	if err != nil {
+		return nil, err
+	}
+	configure(d, chip)
+	if recordFile != "" {
+		f, err := os.Create(recordFile)
+		if err != nil {
+			d.Close()
+			return nil, err
+		}
+		d.Record(f) // closed on exit
+	}
+	return d, nil
+}
+
+// replayer replays the recording given by -replay.
+var replayer *eeprom.Replayer
+
+// replayDone returns an error if the recording given by -replay diverged or
+// was not replayed in its entirety.
+func replayDone() error {
+	if replayer == nil {
+		return nil
+	}
+	return replayer.Done()
+}
+
+// openReplayer returns a device that replays the recording in the named file.
+func openReplayer(name string) (*eeprom.Device, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := eeprom.NewReplayer(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	replayer = p
+	return eeprom.NewDevice(p), nil
+}
+
+// openDevices opens every supported device. Errors opening individual
+// devices are returned by device ID.
+func openDevices() ([]*eeprom.Device, map[string]error, error) {
+	var devices []*eeprom.Device
+	var errs = make(map[string]error)
+
+	chip, err := lookupChip()
+	if err != nil {
+		return nil, nil, err
+	}
+	if recordFile != "" || replayFile != "" {
+		return nil, nil, errors.New("-record and -replay require a single device")
+	}
+	if simFile != "" {
+		d, err := openSimulator(simFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		configure(d, chip)
+		return []*eeprom.Device{d}, errs, nil
+	}
+
+	err = eeprom.Walk(func(d *eeprom.Device) error {
+		if err := d.Open(); err != nil {
+			errs[d.ID()] = err
+			return nil
+		}
+		configure(d, chip)
+		devices = append(devices, d)
+		return nil
+	})
+	return devices, errs, err
+}
+
+// matchDevice reports whether the device is identified by id, which may be a
+// bus:address pair, port path, or serial number. An empty id matches any
+// device.
+func matchDevice(d *eeprom.Device, id string) bool {
+	if id == "" || id == d.ID() {
+		return true
+	}
+	info, err := d.Info()
+	return err == nil && info.Matches(id)
+}
+
+func lookupChip() (*eeprom.Chip, error) {
+	if chipName == "" {
+		return nil, nil
+	}
+	chip := eeprom.LookupChip(chipName)
+	if chip == nil {
+		return nil, errors.New("unknown chip: " + chipName)
+	}
+	return chip, nil
+}
+
+// configure applies settings given by global flags to the device.
+func configure(d *eeprom.Device, chip *eeprom.Chip) {
+	d.SetChip(chip)
+	timeouts := eeprom.Timeouts{
+		Transfer: transferTimeout,
+		PerByte:  byteTimeout,
+		Status:   statusTimeout,
+		Settle:   settleTime,
+	}
+	if settleTime == 0 {
+		timeouts.Settle = -1 // do not wait
+	}
+	d.SetTimeouts(timeouts)
+	if t := newTracer(d); t != nil {
+		d.SetTracer(t)
+	}
+	if retries > 0 {
+		d.SetRetryPolicy(&eeprom.RetryPolicy{
+			Attempts: retries + 1,
+			Backoff:  backoff,
+		})
+	}
+}
+
+func findDevice() (*eeprom.Device, error) {
 	var device *eeprom.Device
 
+	if simFile != "" {
+		return openSimulator(simFile)
+	}
+	if replayFile != "" {
+		return openReplayer(replayFile)
+	}
+
 	err := eeprom.Walk(func(d *eeprom.Device) error {
-		if device == nil && (deviceID == "" || deviceID == d.ID()) {
+		if device == nil && matchDevice(d, deviceID) {
 			device = d
 			return d.Open()
 		}
 		return nil
 	})
+	if err == nil && device == nil {
+		err = errors.New("device not found: " + deviceID)
+	}
 	return device, err
 }
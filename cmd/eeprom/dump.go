@@ -25,10 +25,9 @@ package main
 
 import (
 	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
-
-	"github.com/sstallion/go-eeprom"
 )
 
 var dumpStart, dumpCount int
@@ -37,23 +36,28 @@ func init() {
 	cmd := &command{
 		name: "dump",
 		exec: dump,
-		help: `usage: eeprom dump [-start addr] [-count n] [file]
+		help: `usage: eeprom dump [-chip name] [-start addr] [-count n] [file]
 
 The dump command reads data from the device and emits a hexdump to standard
 output. If specified, dump will write the contents of the device to the given
-file, creating it if necessary.
+file, creating it if necessary. If standard error is a terminal, progress is
+displayed while reading.
 
 The flags are:
 
+    -chip name
+		chip to use, which determines capacity and page size; see
+		"eeprom chips" for a list of supported chips.
     -start addr
 		starting address; by default this is 0.
     -count n
 		number of bytes to read; by default this is the maximum
-		number of bytes supported by the device.
+		number of bytes supported by the device or chip.
 `,
 	}
 	cmd.flag.IntVar(&dumpStart, "start", 0, "")
 	cmd.flag.IntVar(&dumpCount, "count", 0, "")
+	addChipFlag(cmd)
 	addCommand(cmd)
 }
 
@@ -78,11 +82,20 @@ func dump(args ...string) error {
 	}
 	defer d.Close()
 
+	if dumpStart < 0 || int64(dumpStart) >= d.Size() {
+		return fmt.Errorf("start address %#x out of range", dumpStart)
+	}
+	if dumpCount < 0 {
+		return errUsage
+	}
 	if dumpCount == 0 {
-		dumpCount = eeprom.MaxBytes - dumpStart
+		dumpCount = int(d.Size()) - dumpStart
 	}
 	data := make([]byte, dumpCount)
-	if err := d.Read(uint16(dumpStart), data); err != nil {
+	done := newProgressBar("dump").attach(d)
+	err = d.ReadExtended(uint32(dumpStart), data)
+	done()
+	if err != nil {
 		d.Reset()
 		return err
 	}
@@ -24,15 +24,24 @@
 package main
 
 func init() {
-	addCommand(&command{
+	cmd := &command{
 		name: "erase",
 		exec: erase,
-		help: `usage: eeprom erase
+		help: `usage: eeprom erase [-chip name]
 
 The erase command issues a chip erase; all data on the device will be
-destroyed.
+destroyed. If the chip does not support chip erase, 0xff is written to every
+address instead.
+
+The flags are:
+
+    -chip name
+		chip to use, which determines capacity and page size; see
+		"eeprom chips" for a list of supported chips.
 `,
-	})
+	}
+	addChipFlag(cmd)
+	addCommand(cmd)
 }
 
 func erase(...string) error {
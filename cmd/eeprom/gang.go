@@ -0,0 +1,115 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+var gangStart, gangPagesize int
+
+func init() {
+	cmd := &command{
+		name: "gang",
+		exec: gang,
+		help: `usage: eeprom gang [-chip name] [-start addr] [-pagesize n] file
+
+The gang command programs the contents of the specified file on every attached
+device concurrently. Each device is erased, written, and verified; a failure
+on one device does not affect the others. A summary of the results is printed
+once all devices have finished, and the command fails if any device failed.
+
+The flags are:
+
+    -chip name
+		chip to use, which determines capacity and page size; see
+		"eeprom chips" for a list of supported chips.
+    -start addr
+		starting address; by default this is 0.
+    -pagesize n
+		page size to use when writing; by default the page size of
+		the chip is used, otherwise page writes are disabled for
+		compatibility.
+`,
+	}
+	cmd.flag.IntVar(&gangStart, "start", 0, "")
+	cmd.flag.IntVar(&gangPagesize, "pagesize", 0, "")
+	addChipFlag(cmd)
+	addCommand(cmd)
+}
+
+func gang(args ...string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	devices, errs, err := openDevices()
+	if err != nil {
+		return err
+	}
+	results := eeprom.Gang(context.Background(), devices, func(ctx context.Context, d *eeprom.Device) error {
+		defer d.Close()
+
+		d.SetPageSize(gangPagesize)
+		err := d.Program(ctx, uint32(gangStart), data)
+		if err != nil {
+			d.Reset()
+		}
+		return err
+	})
+
+	var failed int
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tTIME\tRESULT")
+	for _, r := range results {
+		result := "ok"
+		if r.Err != nil {
+			result = r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\n", r.Device.ID(), r.Elapsed.Round(time.Millisecond), result)
+	}
+	for id, err := range errs {
+		fmt.Fprintf(w, "%s\t-\t%v\n", id, err)
+		failed++
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if total := len(results) + len(errs); failed > 0 {
+		return fmt.Errorf("%d of %d devices failed", failed, total)
+	}
+	return nil
+}
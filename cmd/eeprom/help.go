@@ -37,20 +37,79 @@ http://github.com/sstallion/usb-eeprom/wiki/Protocol.
 
 Usage:
 
-	eeprom [-id device] command [arguments]
+	eeprom [-id device] [-match list] [-retries n [-backoff d]]
+		[-timeout d] [-bytetimeout d] [-statustimeout d] [-settle d]
+		[-sim file [-fault list]] [-record file | -replay file]
+		[-trace] [-pcap file] command [arguments]
 
 The flags are:
 
     -id device
-		identifies device to use; by default the first supported
-		device is selected.
+		identifies device to use by bus:address, port path, or
+		serial number; see "eeprom list". By default the first
+		supported device is selected.
+    -match list
+		identifies supported devices. list is a comma-separated list
+		of vid:pid[:interface[:endpoint]] specifications, where each
+		number is hexadecimal. By default this is taken from the
+		EEPROM_MATCH environment variable, otherwise 04d8:f4cd:0:1.
+    -retries n
+		number of times a failed transfer is retried after resetting
+		the device; by default transfers are not retried.
+    -backoff d
+		delay before retrying a failed transfer, which is doubled
+		after each retry; by default this is 1s.
+    -timeout d
+		time allowed for each packet transferred; by default this
+		is 2.5s.
+    -bytetimeout d
+		additional time allowed for each byte of a packet, which
+		accommodates chips with long write cycles; by default no
+		time is added.
+    -statustimeout d
+		time allowed for the status word reported once a command is
+		complete; by default this is the value of -timeout, or a
+		write cycle of the chip for each byte of a packet plus
+		100ms if that is longer.
+    -settle d
+		time allowed for the device to settle after a reset; by
+		default this is 500ms.
+    -sim file
+		use a simulated device backed by file rather than an
+		attached device; file is created if necessary.
+    -fault list
+		inject faults into a simulated device. list is a
+		comma-separated list of name=value pairs, where name is one
+		of short (bytes per transfer), timeout or fail (bytes
+		transferred before failing), status (status word XOR mask),
+		stuckhigh or stucklow (stuck data bit mask), drop (drop
+		every nth page), or stop (bytes written before a write stops
+		short).
+    -record file
+		record the traffic exchanged with the device to file.
+    -replay file
+		use a device that replays the traffic recorded in file
+		rather than an attached device; the command fails if it
+		diverges from the recording or does not replay all of it.
+    -trace
+		print each command, bulk transfer and status word
+		exchanged with the device on standard error.
+    -pcap file
+		write each bulk transfer exchanged with the device to file
+		in pcap format using the Linux usbmon link type.
 
 The commands are:
 
+    blank	check that device is blank
+    chips	list supported chips
     dump	dump contents of device
     erase	erase contents of device
+    gang	program every device concurrently
+    list	list attached devices
     reset	hard reset device
+    sdp		enable or disable software data protection
     verify	verify contents of device
+    watch	watch for device arrival and removal
     write	write file to device
 
 Use "eeprom help [command]" for more information about a command.
@@ -30,6 +30,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/sstallion/go-eeprom"
 )
 
 var errUsage = errors.New("usage")
@@ -62,6 +64,21 @@ func main() {
 	flag.Usage = usage()
 	flag.Parse()
 
+	if matchSpec != "" {
+		matches, err := parseMatches(matchSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := eeprom.SetMatches(matches...); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if pcapFile != "" {
+		if err := openPcap(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	if flag.NArg() > 0 {
 		for _, cmd := range commands {
 			if cmd.name != flag.Args()[0] {
@@ -76,6 +93,9 @@ func main() {
 				}
 				log.Fatal(err)
 			}
+			if err := replayDone(); err != nil {
+				log.Fatal(err)
+			}
 			os.Exit(0)
 		}
 	}
@@ -0,0 +1,80 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+const progressWidth = 40
+
+// progressBar draws the progress of a transfer on standard error.
+type progressBar struct {
+	label   string
+	percent int
+	drawn   bool
+}
+
+// newProgressBar returns a progressBar, or nil if standard error is not a
+// terminal or is used for tracing.
+func newProgressBar(label string) *progressBar {
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 || traceFlag {
+		return nil
+	}
+	return &progressBar{label: label, percent: -1}
+}
+
+// attach arranges for the progress of transfers made by d to be drawn. The
+// returned function must be called once the transfer is complete.
+func (p *progressBar) attach(d *eeprom.Device) func() {
+	if p == nil {
+		return func() {}
+	}
+	d.SetProgress(p.update)
+	return p.finish
+}
+
+func (p *progressBar) update(n, total int) {
+	percent := n * 100 / total
+	if percent == p.percent {
+		return
+	}
+	p.percent = percent
+
+	fill := n * progressWidth / total
+	fmt.Fprintf(os.Stderr, "\r%-6s [%s%s] %3d%% %d/%d", p.label,
+		strings.Repeat("=", fill), strings.Repeat(" ", progressWidth-fill), percent, n, total)
+	p.drawn = true
+}
+
+func (p *progressBar) finish() {
+	if p.drawn {
+		fmt.Fprintln(os.Stderr)
+	}
+}
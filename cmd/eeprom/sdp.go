@@ -0,0 +1,73 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package main
+
+import "errors"
+
+func init() {
+	cmd := &command{
+		name: "sdp",
+		exec: sdp,
+		help: `usage: eeprom sdp -chip name enable|disable
+
+The sdp command enables or disables Software Data Protection. While enabled,
+the chip ignores writes. The command sequence depends on the chip, which must
+be given. A byte of the chip is then written and restored to confirm that the
+sequence took effect.
+
+The flags are:
+
+    -chip name
+		chip to use, which determines the command sequence; see
+		"eeprom chips" for a list of supported chips.
+`,
+	}
+	addChipFlag(cmd)
+	addCommand(cmd)
+}
+
+func sdp(args ...string) error {
+	if len(args) != 1 || args[0] != "enable" && args[0] != "disable" {
+		return errUsage
+	}
+	if chipName == "" {
+		return errors.New("sdp requires -chip")
+	}
+
+	d, err := openDevice()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if args[0] == "enable" {
+		err = d.EnableSDP()
+	} else {
+		err = d.DisableSDP()
+	}
+	if err != nil {
+		d.Reset()
+	}
+	return err
+}
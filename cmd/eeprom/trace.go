@@ -0,0 +1,86 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+var (
+	traceFlag bool
+	pcapFile  string
+)
+
+// pcapWriter writes the transfers of every device to the file given by -pcap.
+var pcapWriter *eeprom.PcapWriter
+
+func init() {
+	flag.BoolVar(&traceFlag, "trace", false, "")
+	flag.StringVar(&pcapFile, "pcap", "", "")
+}
+
+// openPcap creates the file given by -pcap. The file is closed on exit.
+func openPcap() error {
+	f, err := os.Create(pcapFile)
+	if err != nil {
+		return err
+	}
+	pcapWriter, err = eeprom.NewPcapWriter(f)
+	return err
+}
+
+// newTracer returns a tracer for the device as requested by global flags, or
+// nil if the device should not be traced.
+func newTracer(d *eeprom.Device) eeprom.Tracer {
+	var tracers []eeprom.Tracer
+
+	if traceFlag {
+		tracers = append(tracers, tracer{d.ID()})
+	}
+	if pcapWriter != nil {
+		info, _ := d.Info()
+		tracers = append(tracers, pcapWriter.Tracer(info.Bus, info.Address))
+	}
+	if len(tracers) == 0 {
+		return nil
+	}
+	return eeprom.MultiTracer(tracers...)
+}
+
+// tracer prints the traffic between the host and a device on standard error.
+type tracer struct {
+	id string
+}
+
+func (t tracer) Command(c eeprom.CommandTrace)   { t.print(c) }
+func (t tracer) Transfer(x eeprom.TransferTrace) { t.print(x) }
+func (t tracer) Status(s eeprom.StatusTrace)     { t.print(s) }
+
+func (t tracer) print(v fmt.Stringer) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", t.id, v)
+}
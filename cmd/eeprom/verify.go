@@ -34,13 +34,17 @@ func init() {
 	cmd := &command{
 		name: "verify",
 		exec: verify,
-		help: `usage: eeprom verify [-start addr] [-count n] file
+		help: `usage: eeprom verify [-chip name] [-start addr] [-count n] file
 
 The verify command reads data from the device and performs a bytewise
-comparison against the specified file.
+comparison against the specified file. If standard error is a terminal,
+progress is displayed while reading.
 
 The flags are:
 
+    -chip name
+		chip to use, which determines capacity and page size; see
+		"eeprom chips" for a list of supported chips.
     -start addr
 		starting address; by default this is 0.
     -count n
@@ -50,6 +54,7 @@ The flags are:
 	}
 	cmd.flag.IntVar(&verifyStart, "start", 0, "")
 	cmd.flag.IntVar(&verifyCount, "count", 0, "")
+	addChipFlag(cmd)
 	addCommand(cmd)
 }
 
@@ -72,7 +77,10 @@ func verify(args ...string) error {
 		verifyCount = len(file)
 	}
 	data := make([]byte, verifyCount)
-	if err := d.Read(uint16(verifyStart), data); err != nil {
+	done := newProgressBar("verify").attach(d)
+	err = d.ReadExtended(uint32(verifyStart), data)
+	done()
+	if err != nil {
 		d.Reset()
 		return err
 	}
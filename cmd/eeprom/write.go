@@ -23,33 +23,50 @@
 
 package main
 
-import "io/ioutil"
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/sstallion/go-eeprom"
+)
 
 var writeStart, writeCount, writePagesize int
 
+var writeDiff bool
+
 func init() {
 	cmd := &command{
 		name: "write",
 		exec: write,
-		help: `usage: eeprom write [-start addr] [-count n] [-pagesize n] file
+		help: `usage: eeprom write [-chip name] [-start addr] [-count n] [-pagesize n] [-diff] file
 
-The write command writes the contents of the specified file to the device.
+The write command writes the contents of the specified file to the device. If
+standard error is a terminal, progress is displayed while writing.
 
 The flags are:
 
+    -chip name
+		chip to use, which determines capacity and page size; see
+		"eeprom chips" for a list of supported chips.
     -start addr
 		starting address; by default this is 0.
     -count n
 		number of bytes to write; by default this is the length of
 		the file.
     -pagesize n
-		page size to use when writing; by default page writes are
-		disabled for compatibility.
+		page size to use when writing; by default the page size of
+		the chip is used, otherwise page writes are disabled for
+		compatibility.
+    -diff
+		only write ranges that differ from the contents of the
+		device. The ranges are printed before they are written.
 `,
 	}
 	cmd.flag.IntVar(&writeStart, "start", 0, "")
 	cmd.flag.IntVar(&writeCount, "count", 0, "")
 	cmd.flag.IntVar(&writePagesize, "pagesize", 0, "")
+	cmd.flag.BoolVar(&writeDiff, "diff", false, "")
+	addChipFlag(cmd)
 	addCommand(cmd)
 }
 
@@ -71,12 +88,35 @@ func write(args ...string) error {
 	if writeCount == 0 || writeCount > len(data) {
 		writeCount = len(data)
 	}
-	if writePagesize > 0 {
-		d.SetPageSize(writePagesize)
-		err = d.WritePages(uint16(writeStart), data[:writeCount])
-	} else {
-		err = d.WriteBytes(uint16(writeStart), data[:writeCount])
+	d.SetPageSize(writePagesize)
+	if writeDiff {
+		return diff(d, uint32(writeStart), data[:writeCount])
+	}
+
+	done := newProgressBar("write").attach(d)
+	err = d.WriteExtended(uint32(writeStart), data[:writeCount])
+	done()
+	if err != nil {
+		d.Reset()
 	}
+	return err
+}
+
+func diff(d *eeprom.Device, start uint32, data []byte) error {
+	done := newProgressBar("read").attach(d)
+	ranges, err := d.Diff(start, data)
+	done()
+	if err != nil {
+		d.Reset()
+		return err
+	}
+	for _, r := range ranges {
+		fmt.Printf("%v (%d bytes)\n", r, r.Len)
+	}
+
+	done = newProgressBar("write").attach(d)
+	err = d.WriteRanges(start, data, ranges)
+	done()
 	if err != nil {
 		d.Reset()
 	}
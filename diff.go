@@ -0,0 +1,138 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"fmt"
+)
+
+// Range describes a contiguous range of addresses.
+type Range struct {
+	Start uint32
+	Len   int
+}
+
+// End returns the address following the last address in the range.
+func (r Range) End() int { return int(r.Start) + r.Len }
+
+func (r Range) String() string {
+	return fmt.Sprintf("%#04x-%#04x", r.Start, r.End()-1)
+}
+
+// Diff reads the device starting at the supplied 32-bit starting address and
+// returns the ranges that differ from the given slice. If a page size is known,
+// ranges are widened to whole pages, clipped to the extent of the slice, so
+// that they may be passed to WriteRanges.
+func (d *Device) Diff(start uint32, data []byte) ([]Range, error) {
+	return d.DiffContext(context.Background(), start, data)
+}
+
+// DiffContext is like Diff, but stops between packets once the given context
+// is done.
+func (d *Device) DiffContext(ctx context.Context, start uint32, data []byte) ([]Range, error) {
+	buf := make([]byte, len(data))
+	if err := d.ReadExtendedContext(ctx, start, buf); err != nil {
+		return nil, err
+	}
+
+	pagesize := d.pageSize()
+	if pagesize <= 0 {
+		pagesize = 1
+	}
+
+	var ranges []Range
+	for i := 0; i < len(data); i++ {
+		if buf[i] == data[i] {
+			continue
+		}
+		// Widen the difference to the enclosing page and merge it
+		// with the previous range if they are contiguous.
+		addr := int(start) + i
+		lo := addr - addr%pagesize
+		hi := lo + pagesize
+		if lo < int(start) {
+			lo = int(start)
+		}
+		if end := int(start) + len(data); hi > end {
+			hi = end
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End() >= lo {
+			ranges[n-1].Len = hi - int(ranges[n-1].Start)
+		} else {
+			ranges = append(ranges, Range{uint32(lo), hi - lo})
+		}
+		i = hi - int(start) - 1
+	}
+	return ranges, nil
+}
+
+// WriteRanges writes the given ranges of a slice whose first element is
+// located at the supplied starting address. Ranges are written with
+// WritePages if a page size is known; otherwise WriteBytes is used. Partial
+// pages at either end of a range are written using WriteBytes. Progress is
+// reported relative to the total length of the ranges.
+func (d *Device) WriteRanges(start uint32, data []byte, ranges []Range) error {
+	return d.WriteRangesContext(context.Background(), start, data, ranges)
+}
+
+// WriteRangesContext is like WriteRanges, but stops between packets once the
+// given context is done.
+func (d *Device) WriteRangesContext(ctx context.Context, start uint32, data []byte, ranges []Range) error {
+	var off, total int
+	for _, r := range ranges {
+		if int(r.Start) < int(start) || r.End() > int(start)+len(data) {
+			return fmt.Errorf("range %v out of bounds", r)
+		}
+		total += r.Len
+	}
+	defer d.relativeProgress(&off, total)()
+
+	for _, r := range ranges {
+		i := int(r.Start) - int(start)
+		if err := d.WriteExtendedContext(ctx, r.Start, data[i:i+r.Len]); err != nil {
+			return err
+		}
+		off += r.Len
+	}
+	return nil
+}
+
+// WriteDiff writes the given slice starting at the supplied starting address,
+// skipping ranges whose contents already match. The ranges written are
+// returned. This reduces both programming time and wear when only a small part
+// of an image has changed.
+func (d *Device) WriteDiff(start uint32, data []byte) ([]Range, error) {
+	return d.WriteDiffContext(context.Background(), start, data)
+}
+
+// WriteDiffContext is like WriteDiff, but stops between packets once the given
+// context is done.
+func (d *Device) WriteDiffContext(ctx context.Context, start uint32, data []byte) ([]Range, error) {
+	ranges, err := d.DiffContext(ctx, start, data)
+	if err != nil {
+		return nil, err
+	}
+	return ranges, d.WriteRangesContext(ctx, start, data, ranges)
+}
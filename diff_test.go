@@ -0,0 +1,114 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		pagesize int
+		want     []eeprom.Range
+	}{
+		{0, []eeprom.Range{{0x05, 1}, {0x45, 2}, {0x200, 1}}},
+		{64, []eeprom.Range{{0x00, 128}, {0x200, 64}}},
+	}
+	for _, tt := range tests {
+		s := eeprom.NewSimulator()
+		d := eeprom.NewDevice(s)
+		d.SetPageSize(tt.pagesize)
+
+		data := make([]byte, 1024)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		copy(s.Memory(), data)
+		data[0x05] ^= 0xff
+		data[0x45] ^= 0xff
+		data[0x46] ^= 0xff
+		data[0x200] ^= 0xff
+
+		ranges, err := d.WriteDiff(0, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ranges, tt.want) {
+			t.Errorf("pagesize %d: expected %v; got %v", tt.pagesize, tt.want, ranges)
+		}
+		if !bytes.Equal(s.Memory()[:len(data)], data) {
+			t.Fatalf("pagesize %d: data mismatch", tt.pagesize)
+		}
+		if ranges, err := d.Diff(0, data); err != nil || len(ranges) != 0 {
+			t.Fatalf("pagesize %d: unexpected ranges %v (%v)", tt.pagesize, ranges, err)
+		}
+	}
+}
+
+func TestDiffClip(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetPageSize(64)
+
+	data := []byte{1, 2, 3, 4}
+	ranges, err := d.Diff(0x7e, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []eeprom.Range{{0x7e, 4}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("expected %v; got %v", want, ranges)
+	}
+}
+
+func TestDiffChip(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C256"))
+
+	data := make([]byte, 0x100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	copy(s.Memory()[0x10:], data)
+	data[0x02] ^= 0xff
+	data[0x90] ^= 0xff
+	data[0xff] ^= 0xff
+
+	ranges, err := d.WriteDiff(0x10, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []eeprom.Range{{0x10, 0x30}, {0x80, 0x40}, {0x100, 0x10}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("expected %v; got %v", want, ranges)
+	}
+	if !bytes.Equal(s.Memory()[0x10:0x110], data) {
+		t.Fatal("data mismatch")
+	}
+}
@@ -24,27 +24,26 @@
 // Package eeprom provides an idiomatic interface to USB EEPROM programmers
 // that conform to http://github.com/sstallion/usb-eeprom/wiki/Protocol. Due
 // to the chip-agnostic nature of the protocol, constraints such as capacity
-// and alignment must be enforced by the caller.
+// and alignment must be enforced by the caller, or by binding a Device to a
+// known Chip using SetChip.
+//
+// Programmers attached to the host are discovered using Walk or First, which
+// use a default Session that is created when first needed. A Device may also
+// be built on top of any Transport using NewDevice, which allows the protocol
+// to be driven without a physical programmer.
 package eeprom
 
-/*
-#cgo LDFLAGS: -lusb-1.0
-#include <libusb-1.0/libusb.h>
-*/
-import "C"
-
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"errors"
-	"fmt"
-	"reflect"
+	"io"
 	"time"
-	"unsafe"
 )
 
 const (
-	// MaxBytes is the maximum amount of addressable data.
+	// MaxBytes is the maximum amount of data addressable by a 16-bit
+	// address. Larger chips are addressed in banks; see BankSize.
 	MaxBytes = 1 << 16
 )
 
@@ -53,76 +52,155 @@ const (
 	idProduct    = 0xf4cd // 28Cxxx EEPROM Programmer
 	interfaceNum = 0
 	endpointNum  = 1
-	endpointIN   = endpointNum | C.LIBUSB_ENDPOINT_IN
-	endpointOUT  = endpointNum | C.LIBUSB_ENDPOINT_OUT
+	endpointIN   = endpointNum | 0x80
+	endpointOUT  = endpointNum | 0x00
 )
 
-type libusbError struct {
-	code C.int
+// Transport is the interface that wraps the low-level operations used to
+// communicate with a programmer. Commands are encoded by Device; a Transport
+// is only responsible for moving bytes across the bulk endpoints.
+type Transport interface {
+	// BulkOut transfers data to the bulk OUT endpoint and returns the
+	// number of bytes transferred. At most one packet is transferred per
+	// call.
+	BulkOut(data []byte, timeout time.Duration) (int, error)
+
+	// BulkIn transfers data from the bulk IN endpoint and returns the
+	// number of bytes transferred. At most one packet is transferred per
+	// call.
+	BulkIn(data []byte, timeout time.Duration) (int, error)
+
+	// MaxPacketSize returns the maximum packet size supported by the bulk
+	// endpoints.
+	MaxPacketSize() int
+
+	// Reset issues a device reset.
+	Reset() error
+
+	// Close releases any resources held by the transport.
+	Close() error
+}
+
+// opener is implemented by transports that must be opened before use.
+type opener interface {
+	open() error
+}
+
+// holder is implemented by transports that must hold a reference to the
+// underlying device to be opened outside of a Walk. The reference is released
+// by Close.
+type holder interface {
+	hold()
 }
 
-func (e *libusbError) Error() string {
-	return fmt.Sprintf("%s (%s)",
-		C.GoString(C.libusb_strerror(C.enum_libusb_error(e.code))),
-		C.GoString(C.libusb_error_name(e.code)))
+// identifier is implemented by transports that can uniquely identify the
+// underlying device.
+type identifier interface {
+	ID() string
 }
 
 // Device represents an attached USB EEPROM programmer.
 type Device struct {
-	dev      *C.libusb_device
-	handle   *C.libusb_device_handle
+	t        Transport
+	chip     *Chip
 	pagesize int
+	progress func(n, total int)
+	retry    *RetryPolicy
+	timeouts Timeouts
+	depth    int // maximum number of packets in flight
+	tracer   Tracer
+	bank     uint8 // bank addressed by 16-bit operations
+	selected bool  // bank has been selected
 }
 
-// ID returns a string suitable for uniquely identifying the device.
+// NewDevice returns a Device that communicates using the given Transport.
+// The returned Device is ready for use; Open need not be called.
+func NewDevice(t Transport) *Device {
+	return &Device{t: t}
+}
+
+// ID returns a string suitable for uniquely identifying the device. An empty
+// string is returned if the underlying Transport cannot identify the device.
 func (d *Device) ID() string {
-	return fmt.Sprintf("%d:%d",
-		C.libusb_get_bus_number(d.dev),
-		C.libusb_get_device_address(d.dev))
+	if t, ok := d.t.(identifier); ok {
+		return t.ID()
+	}
+	return ""
 }
 
+// SetChip binds the device to the given chip. Once bound, the capacity and
+// page alignment of the chip are enforced, and WritePages uses the page size
+// of the chip unless overridden by SetPageSize. A nil chip removes the
+// binding.
+func (d *Device) SetChip(chip *Chip) { d.chip = chip }
+
+// Chip returns the chip bound to the device, or nil if no chip is bound.
+func (d *Device) Chip() *Chip { return d.chip }
+
 // SetPageSize sets the number of bytes written per page by WritePages. By
-// default, the maximum packet size supported by the endpoint is used.
+// default, the page size of the bound chip is used; if no chip is bound or
+// the chip does not support page writes, the maximum packet size supported
+// by the endpoint is used.
 func (d *Device) SetPageSize(pagesize int) { d.pagesize = pagesize }
 
+// SetProgress sets a function that is called as data is transferred by Read,
+// WriteBytes and WritePages. The function is passed the number of bytes
+// transferred so far and the total number of bytes to transfer.
+func (d *Device) SetProgress(fn func(n, total int)) { d.progress = fn }
+
+// relativeProgress arranges for progress to be reported relative to an
+// operation of total bytes, of which *off bytes are already complete, rather
+// than relative to each command. It returns a function that restores the
+// previous progress function.
+func (d *Device) relativeProgress(off *int, total int) func() {
+	progress := d.progress
+	if progress == nil {
+		return func() {}
+	}
+	d.progress = func(n, _ int) { progress(*off+n, total) }
+	return func() { d.progress = progress }
+}
+
 // Open opens an attached device and claims the interface. To ensure proper
-// reference counting, Open must be called within the context of a Walk.
+// reference counting, Open must be called within the context of a Walk unless
+// the device was returned by List.
 func (d *Device) Open() error {
-	if err := C.libusb_open(d.dev, &d.handle); err != C.LIBUSB_SUCCESS {
-		return &libusbError{err}
-	}
-	if err := C.libusb_claim_interface(d.handle, interfaceNum); err != C.LIBUSB_SUCCESS {
-		C.libusb_close(d.handle)
-		return &libusbError{err}
+	if t, ok := d.t.(opener); ok {
+		return t.open()
 	}
 	return nil
 }
 
-// Close releases the interface and closes the device. A device may not be
-// opened again after calling this method. Returned errors may be safely
-// ignored.
+// Close releases the interface and closes the device. If the device was
+// returned by List, Close also releases the reference held to the device,
+// whether or not it was opened. A device may not be opened again after
+// calling this method. Returned errors may be safely ignored.
 func (d *Device) Close() error {
-	defer C.libusb_close(d.handle)
-
-	if err := C.libusb_release_interface(d.handle, interfaceNum); err != C.LIBUSB_SUCCESS {
-		return &libusbError{err}
-	}
-	return nil
+	return d.t.Close()
 }
 
-// Reset issues a device reset. This method may be called after a failed
-// transfer to reset the interface. Returned errors may be safely ignored.
+// Reset issues a device reset and waits for the device to settle. This method
+// may be called after a failed transfer to reset the interface. Returned
+// errors may be safely ignored.
 func (d *Device) Reset() error {
-	defer time.Sleep(500 * time.Millisecond) // wait for device to settle
+	defer d.settle()
 
-	if err := C.libusb_reset_device(d.handle); err != C.LIBUSB_SUCCESS {
-		return &libusbError{err}
-	}
-	return nil
+	d.selected = false
+	return d.t.Reset()
 }
 
 // Read reads into the given slice at the supplied starting address.
 func (d *Device) Read(start uint16, data []byte) error {
+	return d.ReadContext(context.Background(), start, data)
+}
+
+// ReadContext is like Read, but stops between packets once the given context
+// is done.
+func (d *Device) ReadContext(ctx context.Context, start uint16, data []byte) error {
+	return d.do(ctx, start, data, 1, d.read)
+}
+
+func (d *Device) read(ctx context.Context, start uint16, data []byte) error {
 	var b bytes.Buffer
 	var n = uint16(len(data))
 
@@ -133,17 +211,30 @@ func (d *Device) Read(start uint16, data []byte) error {
 	if err := d.validate(start, data); err != nil {
 		return err
 	}
-	if err := d.transfer(endpointOUT, b.Bytes()); err != nil {
-		return err
+	if err := d.command(ctx, b.Bytes()); err != nil {
+		return interrupted(ctx, start, 0, err)
 	}
-	if err := d.transfer(endpointIN, data); err != nil {
-		return err
+	if m, err := d.transferN(ctx, endpointIN, data, 0, true, d.timeouts.transfer(), d.progress); err != nil {
+		return interrupted(ctx, start, m, err)
+	}
+	if err := d.verify(ctx, start+n); err != nil {
+		return interrupted(ctx, start, len(data), err)
 	}
-	return d.verify(start + n)
+	return nil
 }
 
 // WriteBytes writes the given slice starting at the supplied starting address.
 func (d *Device) WriteBytes(start uint16, data []byte) error {
+	return d.WriteBytesContext(context.Background(), start, data)
+}
+
+// WriteBytesContext is like WriteBytes, but stops between packets once the
+// given context is done.
+func (d *Device) WriteBytesContext(ctx context.Context, start uint16, data []byte) error {
+	return d.do(ctx, start, data, 1, d.writeBytes)
+}
+
+func (d *Device) writeBytes(ctx context.Context, start uint16, data []byte) error {
 	var b bytes.Buffer
 	var n = uint16(len(data))
 
@@ -154,17 +245,34 @@ func (d *Device) WriteBytes(start uint16, data []byte) error {
 	if err := d.validate(start, data); err != nil {
 		return err
 	}
-	if err := d.transfer(endpointOUT, b.Bytes()); err != nil {
-		return err
+	if err := d.command(ctx, b.Bytes()); err != nil {
+		return interrupted(ctx, start, 0, err)
 	}
-	if err := d.transfer(endpointOUT, data); err != nil {
-		return err
+	if m, err := d.transferN(ctx, endpointOUT, data, 0, false, d.timeouts.transfer(), d.progress); err != nil {
+		return interrupted(ctx, start, m, err)
+	}
+	if err := d.verify(ctx, start+n); err != nil {
+		return interrupted(ctx, start, len(data), err)
 	}
-	return d.verify(start + n)
+	return nil
 }
 
 // WritePages writes the given slice starting at the supplied starting address.
 func (d *Device) WritePages(start uint16, data []byte) error {
+	return d.WritePagesContext(context.Background(), start, data)
+}
+
+// WritePagesContext is like WritePages, but stops between pages once the
+// given context is done.
+func (d *Device) WritePagesContext(ctx context.Context, start uint16, data []byte) error {
+	align := 1
+	if d.chip != nil && d.chip.PageAlign > 0 {
+		align = d.chip.PageAlign
+	}
+	return d.do(ctx, start, data, align, d.writePages)
+}
+
+func (d *Device) writePages(ctx context.Context, start uint16, data []byte) error {
 	var b bytes.Buffer
 	var n = uint16(len(data))
 
@@ -175,143 +283,229 @@ func (d *Device) WritePages(start uint16, data []byte) error {
 	if err := d.validate(start, data); err != nil {
 		return err
 	}
-	if err := d.transfer(endpointOUT, b.Bytes()); err != nil {
-		return err
+	pagesize := d.pageSize()
+	if d.chip != nil && d.chip.PageAlign > 0 && int(start)%d.chip.PageAlign != 0 {
+		return ErrUnaligned
 	}
-	if err := d.transferN(endpointOUT, data, d.pagesize); err != nil {
-		return err
+	if err := d.command(ctx, b.Bytes()); err != nil {
+		return interrupted(ctx, start, 0, err)
+	}
+	if m, err := d.transferN(ctx, endpointOUT, data, pagesize, true, d.timeouts.transfer(), d.progress); err != nil {
+		return interrupted(ctx, start, m, err)
+	}
+	if err := d.verify(ctx, start+n); err != nil {
+		return interrupted(ctx, start, len(data), err)
 	}
-	return d.verify(start + n)
+	return nil
 }
 
-// Erase issues a Chip Erase.
+// Erase issues a Chip Erase. If the device is bound to a chip that does not
+// support chip erase, the chip is instead erased by writing 0xff to every
+// address.
 func (d *Device) Erase() error {
+	return d.EraseContext(context.Background())
+}
+
+// EraseContext is like Erase, but gives up waiting for the device once the
+// given context is done.
+func (d *Device) EraseContext(ctx context.Context) error {
+	if d.chip != nil && !d.chip.ChipErase {
+		return d.WriteExtendedContext(ctx, 0, bytes.Repeat([]byte{Blank}, d.chip.Capacity))
+	}
+	return d.withRetry(ctx, func() error { return d.erase(ctx) })
+}
+
+func (d *Device) erase(ctx context.Context) error {
 	var b bytes.Buffer
 
 	b.WriteByte('Z')
 
-	if err := d.transfer(endpointOUT, b.Bytes()); err != nil {
-		return err
+	if err := d.command(ctx, b.Bytes()); err != nil {
+		return interrupted(ctx, 0, 0, err)
+	}
+	if err := d.verify(ctx, 0); err != nil {
+		return interrupted(ctx, 0, 0, err)
 	}
-	return d.verify(0)
+	return nil
 }
 
 func (d *Device) validate(start uint16, data []byte) error {
 	if len(data) == 0 {
-		return errors.New("no data")
+		return ErrNoData
 	}
-	if int(start)+len(data) > MaxBytes {
-		return errors.New("too much data")
+	if end := int64(start) + int64(len(data)); end > d.Size() || end > MaxBytes {
+		return ErrTooMuchData
 	}
 	return nil
 }
 
-func (d *Device) transfer(endpoint uint8, data []byte) error {
-	return d.transferN(endpoint, data, 0)
+// pageSize returns the page size used by WritePages. Pages of the bound chip
+// that do not fit in a single packet are written in packet-sized parts.
+func (d *Device) pageSize() int {
+	if d.pagesize == 0 && d.chip != nil {
+		if m := d.t.MaxPacketSize(); d.chip.PageSize > m {
+			return m
+		}
+		return d.chip.PageSize
+	}
+	return d.pagesize
+}
+
+// command sends the given command packet to the device.
+func (d *Device) command(ctx context.Context, cmd []byte) error {
+	d.traceCommand(cmd)
+	_, err := d.transfer(ctx, endpointOUT, cmd, d.timeouts.transfer())
+	return err
 }
 
-func (d *Device) transferN(endpoint uint8, data []byte, n int) error {
-	if m := int(C.libusb_get_max_packet_size(d.dev, C.uchar(endpoint))); n == 0 {
+func (d *Device) transfer(ctx context.Context, endpoint uint8, data []byte, base time.Duration) (int, error) {
+	return d.transferN(ctx, endpoint, data, 0, false, base, nil)
+}
+
+// transferN transfers data in packets of n bytes, returning the number of
+// bytes transferred. Each packet times out after the base timeout, scaled by
+// the length of the packet. The context is checked before each packet, and
+// its deadline shortens the timeout of the final transfer if necessary. If
+// progress is non-nil, it is called after each packet. If queue is true,
+// packets are queued if supported by the transport.
+func (d *Device) transferN(ctx context.Context, endpoint uint8, data []byte, n int, queue bool, base time.Duration, progress func(int, int)) (int, error) {
+	if m := d.t.MaxPacketSize(); n == 0 {
 		n = m
 	} else if n > m {
-		return errors.New("invalid packet size")
+		return 0, ErrPacketSize
+	}
+	if q, ok := d.t.(queuer); ok && queue && len(data) > n && d.queueDepth() > 1 {
+		return d.transferQueued(ctx, q, endpoint, data, n, base, progress)
 	}
 
+	xfer := d.t.BulkOut
+	if endpoint == endpointIN {
+		xfer = d.t.BulkIn
+	}
 	for len, off := len(data), 0; len > 0; {
-		var transferred int
-
+		if err := ctx.Err(); err != nil {
+			return off, err
+		}
 		if n > len {
 			n = len
 		}
-		if err := C.libusb_bulk_transfer(d.handle, C.uchar(endpoint), (*C.uchar)(&data[off]), C.int(n),
-			(*C.int)(unsafe.Pointer(&transferred)), 2500); err != C.LIBUSB_SUCCESS {
-			return &libusbError{err}
+		start := time.Now()
+		transferred, err := xfer(data[off:off+n], timeout(ctx, d.timeouts.scale(base, n)))
+		d.traceTransfer(endpoint, data[off:off+n], transferred, start, err)
+		if err != nil {
+			return off + transferred, err
+		}
+		if transferred == 0 {
+			return off, io.ErrNoProgress
 		}
 		len -= transferred
 		off += transferred
+		if progress != nil {
+			progress(off, off+len)
+		}
 	}
-	return nil
+	return len(data), nil
 }
 
-func (d *Device) verify(expected uint16) error {
+func (d *Device) verify(ctx context.Context, expected uint16) error {
 	var status uint16
 	var data = []byte{0xff, 0xff}
 
-	if err := d.transfer(endpointIN, data); err != nil {
+	if _, err := d.transfer(ctx, endpointIN, data, d.statusTimeout()); err != nil {
 		return err
 	}
 	binary.Read(bytes.NewReader(data), binary.LittleEndian, &status)
+	return d.check(expected, status)
+}
+
+// check compares a status word received from the device with the expected
+// status.
+func (d *Device) check(expected, status uint16) error {
+	if d.tracer != nil {
+		d.tracer.Status(StatusTrace{expected, status})
+	}
 	if status != expected {
-		return fmt.Errorf("expected status %#x; got %#x", expected, status)
+		return &StatusError{expected, status}
 	}
 	return nil
 }
 
-var context *C.libusb_context
-
-func init() {
-	if err := C.libusb_init(&context); err != C.LIBUSB_SUCCESS {
-		panic(&libusbError{err})
+// timeout returns the given timeout, shortened if necessary to honor the
+// deadline of ctx.
+func timeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		if t := time.Until(deadline); t < timeout {
+			timeout = t
+		}
 	}
+	if timeout < time.Millisecond {
+		timeout = time.Millisecond // zero means no timeout
+	}
+	return timeout
 }
 
-/*
-func fini() {
-	C.libusb_exit(context)
-}
-*/
-
-// First returns the first supported device attached to the host. Unlike Walk,
-// the returned Device is opened automatically. This function exists primarily
-// for testing.
-func First() (*Device, error) {
-	handle := C.libusb_open_device_with_vid_pid(context, idVendor, idProduct)
-	if handle == nil {
-		return nil, errors.New("no devices found")
-	}
-	if err := C.libusb_claim_interface(handle, interfaceNum); err != C.LIBUSB_SUCCESS {
-		C.libusb_close(handle)
-		return nil, &libusbError{err}
+// interrupted returns an InterruptedError if err occurred because ctx is
+// done; otherwise err is returned unchanged.
+func interrupted(ctx context.Context, start uint16, n int, err error) error {
+	if ctx.Err() == nil {
+		return err
 	}
-	return &Device{
-		dev:    C.libusb_get_device(handle),
-		handle: handle,
-	}, nil
+	return &InterruptedError{uint32(start), n, ctx.Err()}
 }
 
-// Walk calls the specified function for each supported device attached to the
-// host. To ensure proper reference counting, Open must be called within the
-// context of a Walk.
-func Walk(fn func(*Device) error) error {
-	var list **C.libusb_device
-	var found int
+// List returns each supported device attached to the host. Unlike devices
+// passed to Walk, the returned devices hold a reference to the underlying
+// device, and may be opened after List returns. Close must be called on each
+// returned device, whether or not it was opened.
+func (s *Session) List() ([]*Device, error) {
+	var devices []*Device
 
-	n := C.libusb_get_device_list(context, &list)
-	if n < C.LIBUSB_SUCCESS {
-		return &libusbError{C.int(n)}
+	err := s.Walk(func(d *Device) error {
+		if t, ok := d.t.(holder); ok {
+			t.hold()
+		}
+		devices = append(devices, d)
+		return nil
+	})
+	if err == ErrNoDevices {
+		err = nil
 	}
-	defer C.libusb_free_device_list(list, 1)
+	return devices, err
+}
 
-	h := reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(list)),
-		Len:  int(n),
-		Cap:  int(n),
+// List calls List on the default session.
+func List() ([]*Device, error) {
+	s, err := DefaultSession()
+	if err != nil {
+		return nil, err
 	}
-	for _, dev := range *(*[]*C.libusb_device)(unsafe.Pointer(&h)) {
-		var desc C.struct_libusb_device_descriptor
+	return s.List()
+}
 
-		if err := C.libusb_get_device_descriptor(dev, &desc); err != C.LIBUSB_SUCCESS {
-			return &libusbError{err}
-		}
-		if desc.idVendor == idVendor && desc.idProduct == idProduct {
-			if err := fn(&Device{dev: dev}); err != nil {
-				return err
-			}
-			found++
+// First returns the first supported device attached to the host. Unlike Walk,
+// the returned Device is opened automatically. This function exists primarily
+// for testing.
+func (s *Session) First() (*Device, error) {
+	var device *Device
+
+	err := s.Walk(func(d *Device) error {
+		if device == nil {
+			device = d
+			return d.Open()
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if found == 0 {
-		return errors.New("no devices found")
+	return device, nil
+}
+
+// First calls First on the default session.
+func First() (*Device, error) {
+	s, err := DefaultSession()
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return s.First()
 }
@@ -24,34 +24,188 @@
 package eeprom_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"testing"
+	"time"
 
 	"github.com/sstallion/go-eeprom"
 )
 
+var hardware = flag.Bool("hardware", false, "run tests against an attached programmer")
+
 type dataCommand func(*eeprom.Device, uint16, []byte) error
 
-func TestReset(t *testing.T) {
+// openDevice returns a simulated device unless the -hardware flag is given,
+// in which case the first attached programmer is returned.
+func openDevice(tb testing.TB) *eeprom.Device {
+	if !*hardware {
+		return eeprom.NewDevice(eeprom.NewSimulator())
+	}
 	if testing.Short() {
-		t.SkipNow()
+		tb.SkipNow()
 	}
 	d, err := eeprom.First()
 	if err != nil {
+		tb.Fatal(err)
+	}
+	return d
+}
+
+// recorder is a Transport that records data transferred to the OUT endpoint
+// and replays canned data from the IN endpoint.
+type recorder struct {
+	out     bytes.Buffer
+	packets int
+	in      []byte
+}
+
+func (r *recorder) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	r.packets++
+	return r.out.Write(data)
+}
+
+func (r *recorder) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	n := copy(data, r.in)
+	r.in = r.in[n:]
+	return n, nil
+}
+
+func (r *recorder) MaxPacketSize() int { return 64 }
+func (r *recorder) Reset() error       { return nil }
+func (r *recorder) Close() error       { return nil }
+
+func TestTransport(t *testing.T) {
+	data := []byte{0xde, 0xad, 0xbe, 0xef}
+	tests := []struct {
+		name     string
+		cmd      dataCommand
+		pagesize int
+		in       []byte
+		out      []byte
+		packets  int
+	}{
+		{"Read", (*eeprom.Device).Read, 0,
+			[]byte{0xde, 0xad, 0xbe, 0xef, 0x38, 0x12},
+			[]byte{'R', 0x34, 0x12, 0x03, 0x00}, 1},
+		{"WriteBytes", (*eeprom.Device).WriteBytes, 0,
+			[]byte{0x38, 0x12},
+			[]byte{'W', 0x34, 0x12, 0x03, 0x00, 0xde, 0xad, 0xbe, 0xef}, 2},
+		{"WritePages", (*eeprom.Device).WritePages, 2,
+			[]byte{0x38, 0x12},
+			[]byte{'P', 0x34, 0x12, 0x03, 0x00, 0xde, 0xad, 0xbe, 0xef}, 3},
+	}
+	for _, test := range tests {
+		r := &recorder{in: test.in}
+		d := eeprom.NewDevice(r)
+		d.SetPageSize(test.pagesize)
+
+		buf := make([]byte, len(data))
+		if test.name != "Read" {
+			copy(buf, data)
+		}
+		if err := test.cmd(d, 0x1234, buf); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.Equal(r.out.Bytes(), test.out) {
+			t.Errorf("%s: expected % x; got % x", test.name, test.out, r.out.Bytes())
+		}
+		if r.packets != test.packets {
+			t.Errorf("%s: expected %d packets; got %d", test.name, test.packets, r.packets)
+		}
+		if !bytes.Equal(buf, data) {
+			t.Errorf("%s: expected % x; got % x", test.name, data, buf)
+		}
+	}
+}
+
+// cancelTransport cancels a context after a number of OUT transfers.
+type cancelTransport struct {
+	*eeprom.Simulator
+	n      int
+	cancel context.CancelFunc
+}
+
+func (t *cancelTransport) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	if t.n--; t.n == 0 {
+		t.cancel()
+	}
+	return t.Simulator.BulkOut(data, timeout)
+}
+
+func TestContext(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{"Canceled", 0, 0},
+		{"Command", 1, 0},
+		{"Data", 3, 128},
+	}
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.n == 0 {
+			cancel()
+		}
+		d := eeprom.NewDevice(&cancelTransport{eeprom.NewSimulator(), test.n, cancel})
+
+		err := d.WriteBytesContext(ctx, 0x100, make([]byte, 256))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("%s: expected %v; got %v", test.name, context.Canceled, err)
+		}
+		var e *eeprom.InterruptedError
+		if !errors.As(err, &e) {
+			t.Fatalf("%s: expected *InterruptedError; got %T", test.name, err)
+		}
+		if e.Start != 0x100 || e.N != test.want {
+			t.Errorf("%s: expected %d bytes at %#x; got %d at %#x", test.name, test.want, 0x100, e.N, e.Start)
+		}
+		cancel()
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	if err := d.EraseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v; got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+
+	var calls, last int
+	d.SetProgress(func(n, total int) {
+		if total != 1000 || n <= last || n > total {
+			t.Fatalf("unexpected progress %d/%d after %d", n, total, last)
+		}
+		calls++
+		last = n
+	})
+	if err := d.Read(0, make([]byte, 1000)); err != nil {
 		t.Fatal(err)
 	}
+	if last != 1000 || calls != 16 {
+		t.Fatalf("expected 16 calls ending at 1000; got %d ending at %d", calls, last)
+	}
+}
+
+func TestReset(t *testing.T) {
+	d := openDevice(t)
 	defer d.Close()
 
 	d.Reset()
 }
 
 func TestVerify(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
-	d, err := eeprom.First()
-	if err != nil {
-		t.Fatal(err)
-	}
+	d := openDevice(t)
 	defer d.Close()
 
 	tests := []struct {
@@ -85,12 +239,13 @@ func TestVerify(t *testing.T) {
 	}
 }
 
-func benchmarkDataCommand(b *testing.B, cmd dataCommand, n int) {
-	d, err := eeprom.First()
-	if err != nil {
-		b.Fatal(err)
-	}
+// benchmarkDataCommand benchmarks cmd transferring n bytes with the given
+// queue depth. The Simulator does not queue transfers, so the queued and
+// synchronous variants only differ when run with -hardware.
+func benchmarkDataCommand(b *testing.B, cmd dataCommand, n, depth int) {
+	d := openDevice(b)
 	defer d.Close()
+	d.SetQueueDepth(depth)
 
 	data := make([]byte, n)
 	if err := d.Erase(); err != nil {
@@ -106,22 +261,27 @@ func benchmarkDataCommand(b *testing.B, cmd dataCommand, n int) {
 }
 
 func BenchmarkRead8K(b *testing.B) {
-	benchmarkDataCommand(b, (*eeprom.Device).Read, 8*1024)
+	benchmarkDataCommand(b, (*eeprom.Device).Read, 8*1024, 0)
+}
+
+func BenchmarkRead8KSync(b *testing.B) {
+	benchmarkDataCommand(b, (*eeprom.Device).Read, 8*1024, 1)
 }
 
 func BenchmarkWriteBytes8K(b *testing.B) {
-	benchmarkDataCommand(b, (*eeprom.Device).WriteBytes, 8*1024)
+	benchmarkDataCommand(b, (*eeprom.Device).WriteBytes, 8*1024, 0)
 }
 
 func BenchmarkWritePages8K(b *testing.B) {
-	benchmarkDataCommand(b, (*eeprom.Device).WritePages, 8*1024)
+	benchmarkDataCommand(b, (*eeprom.Device).WritePages, 8*1024, 0)
+}
+
+func BenchmarkWritePages8KSync(b *testing.B) {
+	benchmarkDataCommand(b, (*eeprom.Device).WritePages, 8*1024, 1)
 }
 
 func BenchmarkErase(b *testing.B) {
-	d, err := eeprom.First()
-	if err != nil {
-		b.Fatal(err)
-	}
+	d := openDevice(b)
 	defer d.Close()
 
 	b.ResetTimer()
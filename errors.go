@@ -0,0 +1,133 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by this package. Errors of other types may be compared
+// against these using errors.Is.
+var (
+	ErrNoDevices    = errors.New("no devices found")
+	ErrDisconnected = errors.New("device disconnected")
+	ErrTimeout      = errors.New("transfer timed out")
+	ErrNoData       = errors.New("no data")
+	ErrTooMuchData  = errors.New("too much data")
+	ErrPacketSize   = errors.New("invalid packet size")
+	ErrUnaligned    = errors.New("unaligned page write")
+	ErrNoSDP        = errors.New("software data protection not supported")
+	ErrSDPFailed    = errors.New("software data protection not changed")
+)
+
+// libusb error codes; see enum libusb_error.
+const (
+	codeIO           = -1
+	codeInvalidParam = -2
+	codeAccess       = -3
+	codeNoDevice     = -4
+	codeNotFound     = -5
+	codeBusy         = -6
+	codeTimeout      = -7
+	codeOverflow     = -8
+	codePipe         = -9
+	codeInterrupted  = -10
+	codeNoMem        = -11
+	codeNotSupported = -12
+	codeOther        = -99
+)
+
+// USBError is returned when a USB operation fails. Code holds the libusb
+// error code (LIBUSB_ERROR_*) describing the failure; backends that do not
+// use libusb translate their errors to the equivalent code.
+type USBError struct {
+	Code int
+	Msg  string
+}
+
+func (e *USBError) Error() string { return e.Msg }
+
+// Is reports whether the error is equivalent to ErrTimeout or
+// ErrDisconnected.
+func (e *USBError) Is(target error) bool {
+	switch target {
+	case ErrTimeout:
+		return e.Code == codeTimeout
+	case ErrDisconnected:
+		return e.Code == codeNoDevice
+	}
+	return false
+}
+
+// StatusError is returned when the status word reported by the device does
+// not match the address expected by the host, which indicates that the device
+// failed to complete a command.
+type StatusError struct {
+	Expected uint16
+	Got      uint16
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("expected status %#x; got %#x", e.Expected, e.Got)
+}
+
+// MismatchError is returned when data read from the device does not match the
+// data expected.
+type MismatchError struct {
+	Addr     uint32
+	Expected byte
+	Got      byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%#x: expected %#x; got %#x", e.Addr, e.Expected, e.Got)
+}
+
+// InterruptedError is returned when an operation is interrupted because its
+// context is done. The device should be reset before it is used again.
+type InterruptedError struct {
+	Start uint32 // starting address of the operation
+	N     int    // number of data bytes transferred
+	Err   error  // context error
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted at %#x after %d bytes: %v", int(e.Start)+e.N, e.N, e.Err)
+}
+
+// Unwrap returns the context error.
+func (e *InterruptedError) Unwrap() error { return e.Err }
+
+// DivergenceError is returned by a Replayer when a Device issues a request
+// that differs from the recording.
+type DivergenceError struct {
+	Index    int    // index of the diverging record
+	Expected string // recorded request
+	Got      string // request issued
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("replay diverged at record %d: expected %s; got %s", e.Index, e.Expected, e.Got)
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestUSBError(t *testing.T) {
+	tests := []struct {
+		code   int
+		target error
+		want   bool
+	}{
+		{-7, eeprom.ErrTimeout, true},
+		{-7, eeprom.ErrDisconnected, false},
+		{-4, eeprom.ErrDisconnected, true},
+		{-1, eeprom.ErrTimeout, false},
+	}
+	for _, test := range tests {
+		err := error(&eeprom.USBError{Code: test.code})
+		if got := errors.Is(err, test.target); got != test.want {
+			t.Errorf("code %d: errors.Is(%v): expected %v; got %v", test.code, test.target, test.want, got)
+		}
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	s.SetFaults(eeprom.Faults{StatusMask: 0x00c0})
+
+	err := d.WriteBytes(0x7f00, make([]byte, 0x100))
+	var e *eeprom.StatusError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *StatusError; got %v", err)
+	}
+	if e.Expected != 0x8000 || e.Got != 0x80c0 {
+		t.Fatalf("expected 0x8000, 0x80c0; got %#x, %#x", e.Expected, e.Got)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+
+	if err := d.Read(0, nil); err != eeprom.ErrNoData {
+		t.Errorf("expected %v; got %v", eeprom.ErrNoData, err)
+	}
+	if err := d.Read(1, make([]byte, eeprom.MaxBytes)); err != eeprom.ErrTooMuchData {
+		t.Errorf("expected %v; got %v", eeprom.ErrTooMuchData, err)
+	}
+	d.SetPageSize(1024)
+	if err := d.WritePages(0, make([]byte, 2048)); err != eeprom.ErrPacketSize {
+		t.Errorf("expected %v; got %v", eeprom.ErrPacketSize, err)
+	}
+}
@@ -24,6 +24,9 @@
 package eeprom_test
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/sstallion/go-eeprom"
 )
 
@@ -44,3 +47,48 @@ func Example() {
 		return d.WriteBytes(start, data)
 	})
 }
+
+func ExampleGang() {
+	var start uint32
+	var data []byte
+	var devices []*eeprom.Device
+
+	// Program attached devices concurrently:
+	eeprom.Walk(func(d *eeprom.Device) error {
+		if err := d.Open(); err != nil {
+			return err
+		}
+		devices = append(devices, d)
+		return nil
+	})
+	results := eeprom.Gang(context.Background(), devices, func(ctx context.Context, d *eeprom.Device) error {
+		defer d.Close()
+
+		return d.Program(ctx, start, data)
+	})
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Println(r.Device.ID(), r.Err)
+		}
+	}
+}
+
+func ExampleList() {
+	var pick func([]*eeprom.Device) *eeprom.Device
+
+	// Open a device chosen after enumeration:
+	devices, err := eeprom.List()
+	if err != nil {
+		return
+	}
+	d := pick(devices)
+	for _, other := range devices {
+		if other != d {
+			other.Close()
+		}
+	}
+	if err := d.Open(); err != nil {
+		return
+	}
+	defer d.Close()
+}
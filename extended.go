@@ -0,0 +1,140 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"errors"
+)
+
+// BankSize is the number of bytes addressable within a single bank.
+//
+// Chips larger than MaxBytes are addressed using programmers that support
+// extended addressing. Such programmers accept a Bank Select command, which
+// sets the high address bits used by subsequent commands:
+//
+//	'B' bank
+//
+// The programmer responds with a status word containing the selected bank.
+// Bank Select is never issued to devices that are not bound to a chip larger
+// than MaxBytes.
+const BankSize = MaxBytes
+
+// SetBank sets the bank addressed by Read, WriteBytes and WritePages when the
+// device is bound to a chip larger than MaxBytes. The bank is selected before
+// the next operation, and again after the device is reset. ReadExtended and
+// WriteExtended select banks automatically, and restore the bank set here
+// before they return.
+func (d *Device) SetBank(bank uint8) {
+	d.bank = bank
+	d.selected = false
+}
+
+// Bank returns the bank addressed by Read, WriteBytes and WritePages.
+func (d *Device) Bank() uint8 { return d.bank }
+
+// selectBank issues a Bank Select if the device uses extended addressing and
+// the bank has not been selected since the device was last reset.
+func (d *Device) selectBank(ctx context.Context) error {
+	if d.selected || d.Size() <= MaxBytes {
+		return nil
+	}
+	if err := d.command(ctx, []byte{'B', d.bank}); err != nil {
+		return err
+	}
+	if err := d.verify(ctx, uint16(d.bank)); err != nil {
+		return err
+	}
+	d.selected = true
+	return nil
+}
+
+// ReadExtended reads into the given slice at the supplied 32-bit starting
+// address, selecting banks as needed.
+func (d *Device) ReadExtended(start uint32, data []byte) error {
+	return d.ReadExtendedContext(context.Background(), start, data)
+}
+
+// ReadExtendedContext is like ReadExtended, but stops between packets once the
+// given context is done.
+func (d *Device) ReadExtendedContext(ctx context.Context, start uint32, data []byte) error {
+	return d.banked(ctx, start, data, d.ReadContext)
+}
+
+// WriteExtended writes the given slice starting at the supplied 32-bit
+// starting address, selecting banks as needed. If a page size is known,
+// WritePages is used; otherwise WriteBytes is used. Partial pages at an
+// unaligned address are written using WriteBytes.
+func (d *Device) WriteExtended(start uint32, data []byte) error {
+	return d.WriteExtendedContext(context.Background(), start, data)
+}
+
+// WriteExtendedContext is like WriteExtended, but stops between packets once
+// the given context is done.
+func (d *Device) WriteExtendedContext(ctx context.Context, start uint32, data []byte) error {
+	return d.banked(ctx, start, data, d.write)
+}
+
+// banked performs op on data starting at start, split at bank boundaries.
+func (d *Device) banked(ctx context.Context, start uint32, data []byte,
+	op func(context.Context, uint16, []byte) error) error {
+	if len(data) == 0 {
+		return ErrNoData
+	}
+	if int64(start)+int64(len(data)) > d.Size() {
+		return ErrTooMuchData
+	}
+
+	var off int
+	defer d.relativeProgress(&off, len(data))()
+
+	// Restore the bank addressed by 16-bit operations; it is selected
+	// again by the next such operation.
+	bank := d.bank
+	defer func() {
+		if d.bank != bank {
+			d.SetBank(bank)
+		}
+	}()
+
+	for off < len(data) {
+		addr := start + uint32(off)
+		n := BankSize - int(addr%BankSize)
+		if n > len(data)-off {
+			n = len(data) - off
+		}
+		if bank := uint8(addr / BankSize); bank != d.bank {
+			d.SetBank(bank)
+		}
+		if err := op(ctx, uint16(addr), data[off:off+n]); err != nil {
+			var e *InterruptedError
+			if errors.As(err, &e) {
+				e.Start, e.N = start, off+e.N
+			}
+			return err
+		}
+		off += n
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestExtended(t *testing.T) {
+	s := eeprom.NewExtendedSimulator(128 << 10)
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C010"))
+	if size := d.Size(); size != 128<<10 {
+		t.Fatalf("expected size %d; got %d", 128<<10, size)
+	}
+
+	var last int
+	d.SetProgress(func(n, total int) {
+		if n < last || total != 4096 {
+			t.Fatalf("unexpected progress %d/%d", n, total)
+		}
+		last = n
+	})
+
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	start := uint32(eeprom.BankSize - 1024)
+	if err := d.WriteExtended(start, data); err != nil {
+		t.Fatal(err)
+	}
+	if last != len(data) {
+		t.Fatalf("expected progress %d; got %d", len(data), last)
+	}
+	d.SetProgress(nil)
+	if !bytes.Equal(s.Memory()[start:int(start)+len(data)], data) {
+		t.Fatal("unexpected memory contents")
+	}
+	if d.Bank() != 0 {
+		t.Fatalf("expected bank 0 to be restored; got %d", d.Bank())
+	}
+
+	buf := make([]byte, len(data))
+	if err := d.ReadExtended(start, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Fatal("unexpected data read")
+	}
+
+	// 16-bit operations address the current bank and may not cross it.
+	d.SetBank(1)
+	if err := d.Read(0, buf[:16]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:16], data[1024:1040]) {
+		t.Fatal("unexpected data read from bank 1")
+	}
+	if err := d.Read(0xfff0, buf[:32]); err != eeprom.ErrTooMuchData {
+		t.Fatalf("expected ErrTooMuchData; got %v", err)
+	}
+	if err := d.ReadExtended(128<<10-16, buf[:32]); err != eeprom.ErrTooMuchData {
+		t.Fatalf("expected ErrTooMuchData; got %v", err)
+	}
+}
+
+func TestExtendedBank(t *testing.T) {
+	s := eeprom.NewExtendedSimulator(128 << 10)
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C010"))
+	mem := s.Memory()
+	for i := range mem {
+		mem[i] = byte(i >> 16)
+	}
+
+	// Extended operations restore the bank addressed by 16-bit
+	// operations.
+	d.SetBank(1)
+	buf := make([]byte, 16)
+	if err := d.ReadExtended(0, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != 0 {
+		t.Fatalf("expected data from bank 0; got %#x", buf[0])
+	}
+	if d.Bank() != 1 {
+		t.Fatalf("expected bank 1; got %d", d.Bank())
+	}
+	if err := d.Read(0, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != 1 {
+		t.Fatalf("expected data from bank 1; got %#x", buf[0])
+	}
+}
+
+func TestExtendedReset(t *testing.T) {
+	s := eeprom.NewExtendedSimulator(128 << 10)
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C010"))
+	d.SetRetryPolicy(&eeprom.RetryPolicy{Attempts: 2, ChunkSize: 1024})
+	s.SetFaults(eeprom.Faults{Err: eeprom.ErrTimeout, ErrAfter: 2000})
+
+	// The device must select the bank again after it is reset.
+	data := bytes.Repeat([]byte{0x5a}, 4096)
+	if err := d.WriteExtended(eeprom.BankSize, data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Memory()[eeprom.BankSize:eeprom.BankSize+len(data)], data) {
+		t.Fatal("unexpected memory contents")
+	}
+	if !bytes.Equal(s.Memory()[:len(data)], bytes.Repeat([]byte{0xff}, len(data))) {
+		t.Fatal("unexpected write to bank 0")
+	}
+}
+
+func TestExtendedUnsupported(t *testing.T) {
+	// Bank Select is an invalid command for a Simulator that does not
+	// support extended addressing.
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	d.SetBank(1)
+	if err := d.WriteExtended(0x100, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ReadExtended(eeprom.MaxBytes, []byte{0}); err != eeprom.ErrTooMuchData {
+		t.Fatalf("expected ErrTooMuchData; got %v", err)
+	}
+}
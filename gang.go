@@ -0,0 +1,88 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Program erases the device, writes data starting at the supplied 32-bit
+// starting address, and reads it back to verify that it was written
+// correctly. Banks are selected as needed. A *MismatchError is returned if
+// verification fails.
+func (d *Device) Program(ctx context.Context, start uint32, data []byte) error {
+	if err := d.EraseContext(ctx); err != nil {
+		return err
+	}
+	if err := d.WriteExtendedContext(ctx, start, data); err != nil {
+		return err
+	}
+	buf := make([]byte, len(data))
+	if err := d.ReadExtendedContext(ctx, start, buf); err != nil {
+		return err
+	}
+	for i, b := range data {
+		if buf[i] != b {
+			return &MismatchError{start + uint32(i), b, buf[i]}
+		}
+	}
+	return nil
+}
+
+// GangResult records the outcome of an operation performed by Gang on a
+// single device.
+type GangResult struct {
+	Device  *Device
+	Err     error
+	Elapsed time.Duration
+}
+
+// Gang calls fn concurrently for each of the given devices, and returns the
+// result for each device in the same order. A failure on one device does not
+// affect the others; in particular, the context passed to fn is not canceled
+// when fn fails. For example, to program the same image on every device:
+//
+//	results := eeprom.Gang(ctx, devices, func(ctx context.Context, d *eeprom.Device) error {
+//		return d.Program(ctx, start, data)
+//	})
+func Gang(ctx context.Context, devices []*Device, fn func(context.Context, *Device) error) []GangResult {
+	results := make([]GangResult, len(devices))
+
+	var wg sync.WaitGroup
+	for i, d := range devices {
+		wg.Add(1)
+		go func(r *GangResult, d *Device) {
+			defer wg.Done()
+
+			start := time.Now()
+			r.Device = d
+			r.Err = fn(ctx, d)
+			r.Elapsed = time.Since(start)
+		}(&results[i], d)
+	}
+	wg.Wait()
+	return results
+}
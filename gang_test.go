@@ -0,0 +1,107 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestProgram(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	data := []byte{0x00, 0x7f, 0x80, 0xff}
+	if err := d.Program(context.Background(), 0x10, data); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetFaults(eeprom.Faults{StuckLow: 0x80})
+	var e *eeprom.MismatchError
+	if err := d.Program(context.Background(), 0x10, data); !errors.As(err, &e) {
+		t.Fatalf("expected *MismatchError; got %v", err)
+	}
+	if e.Addr != 0x12 || e.Expected != 0x80 || e.Got != 0x00 {
+		t.Fatalf("unexpected mismatch %v", e)
+	}
+}
+
+func TestProgramExtended(t *testing.T) {
+	s := eeprom.NewExtendedSimulator(128 * 1024)
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C010"))
+
+	data := make([]byte, 128*1024)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := d.Program(context.Background(), 0, data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Memory(), data) {
+		t.Fatal("unexpected memory contents")
+	}
+
+	s.SetFaults(eeprom.Faults{StuckLow: 0x80})
+	var e *eeprom.MismatchError
+	if err := d.Program(context.Background(), 0x1fff0, data[0x1fff0:]); !errors.As(err, &e) {
+		t.Fatalf("expected *MismatchError; got %v", err)
+	}
+	if e.Addr < 0x1fff0 {
+		t.Fatalf("unexpected mismatch %v", e)
+	}
+}
+
+func TestGang(t *testing.T) {
+	sims := []*eeprom.Simulator{
+		eeprom.NewSimulator(),
+		eeprom.NewSimulator(),
+		eeprom.NewSimulator(),
+	}
+	sims[1].SetFaults(eeprom.Faults{Err: eeprom.ErrTimeout, ErrAfter: 100})
+
+	var devices []*eeprom.Device
+	for _, s := range sims {
+		devices = append(devices, eeprom.NewDevice(s))
+	}
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	results := eeprom.Gang(context.Background(), devices, func(ctx context.Context, d *eeprom.Device) error {
+		return d.Program(ctx, 0, data)
+	})
+	for i, r := range results {
+		if r.Device != devices[i] {
+			t.Fatalf("result %d: unexpected device", i)
+		}
+		if failed := i == 1; failed != (r.Err != nil) {
+			t.Fatalf("result %d: unexpected error %v", i, r.Err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+//go:build cgo && !usbfs
+// +build cgo,!usbfs
+
+package eeprom
+
+/*
+#include <stdlib.h>
+#include <libusb-1.0/libusb.h>
+
+extern int hotplugCallback(libusb_context *, libusb_device *, libusb_hotplug_event, void *);
+*/
+import "C"
+
+import (
+	"context"
+	"sync"
+	"unsafe"
+)
+
+// hotplugWatchers maps the user data passed to libusb to the watcher events
+// are queued on; Go pointers may not be retained by C code.
+var (
+	hotplugMu       sync.Mutex
+	hotplugWatchers = make(map[unsafe.Pointer]*hotplugWatcher)
+)
+
+// hotplugWatcher queues events reported by libusb. Callbacks may not block,
+// as they are also invoked when the callback is registered.
+type hotplugWatcher struct {
+	s      *Session
+	mu     sync.Mutex
+	events []Event
+}
+
+func (w *hotplugWatcher) push(e Event) {
+	w.mu.Lock()
+	w.events = append(w.events, e)
+	w.mu.Unlock()
+}
+
+func (w *hotplugWatcher) pop() []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	events := w.events
+	w.events = nil
+	return events
+}
+
+//export hotplugCallback
+func hotplugCallback(_ *C.libusb_context, dev *C.libusb_device, event C.libusb_hotplug_event, key unsafe.Pointer) C.int {
+	hotplugMu.Lock()
+	w := hotplugWatchers[key]
+	hotplugMu.Unlock()
+	if w == nil {
+		return 1 // deregister
+	}
+
+	var desc C.struct_libusb_device_descriptor
+	if err := C.libusb_get_device_descriptor(dev, &desc); err != C.LIBUSB_SUCCESS {
+		return 0
+	}
+	if _, ok := w.s.lookupMatch(uint16(desc.idVendor), uint16(desc.idProduct)); !ok {
+		return 0
+	}
+
+	e := Event{Type: DeviceArrived, ID: (&usbTransport{dev: dev}).ID()}
+	if event == C.LIBUSB_HOTPLUG_EVENT_DEVICE_LEFT {
+		e.Type = DeviceLeft
+	}
+	w.push(e)
+	return 0
+}
+
+func (s *Session) watchHotplug(ctx context.Context) (<-chan Event, error) {
+	if C.libusb_has_capability(C.LIBUSB_CAP_HAS_HOTPLUG) == 0 {
+		return nil, errNoHotplug
+	}
+
+	w := &hotplugWatcher{s: s}
+	key := C.malloc(1)
+	hotplugMu.Lock()
+	hotplugWatchers[key] = w
+	hotplugMu.Unlock()
+
+	var handle C.libusb_hotplug_callback_handle
+	if err := C.libusb_hotplug_register_callback(s.ctx,
+		C.LIBUSB_HOTPLUG_EVENT_DEVICE_ARRIVED|C.LIBUSB_HOTPLUG_EVENT_DEVICE_LEFT,
+		C.LIBUSB_HOTPLUG_ENUMERATE, C.LIBUSB_HOTPLUG_MATCH_ANY, C.LIBUSB_HOTPLUG_MATCH_ANY, C.LIBUSB_HOTPLUG_MATCH_ANY,
+		C.libusb_hotplug_callback_fn(C.hotplugCallback), key, &handle); err != C.LIBUSB_SUCCESS {
+		hotplugMu.Lock()
+		delete(hotplugWatchers, key)
+		hotplugMu.Unlock()
+		C.free(key)
+		return nil, libusbError(err)
+	}
+
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+
+	loop:
+		for {
+			for _, e := range w.pop() {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					break loop
+				}
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			var tv C.struct_timeval
+			tv.tv_usec = 100000
+			C.libusb_handle_events_timeout_completed(s.ctx, &tv, nil)
+		}
+		C.libusb_hotplug_deregister_callback(s.ctx, handle)
+
+		hotplugMu.Lock()
+		delete(hotplugWatchers, key)
+		hotplugMu.Unlock()
+		C.free(key)
+	}()
+	return ch, nil
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import "fmt"
+
+// Speed is the speed at which a device is operating.
+type Speed int
+
+// Speeds reported in DeviceInfo; these match enum libusb_speed.
+const (
+	SpeedUnknown Speed = iota
+	SpeedLow
+	SpeedFull
+	SpeedHigh
+	SpeedSuper
+	SpeedSuperPlus
+)
+
+func (s Speed) String() string {
+	switch s {
+	case SpeedLow:
+		return "1.5 Mbit/s"
+	case SpeedFull:
+		return "12 Mbit/s"
+	case SpeedHigh:
+		return "480 Mbit/s"
+	case SpeedSuper:
+		return "5 Gbit/s"
+	case SpeedSuperPlus:
+		return "10 Gbit/s"
+	}
+	return "unknown"
+}
+
+// DeviceInfo describes an attached device. Unlike ID, PortPath and
+// SerialNumber do not change when a device is re-attached; PortPath remains
+// the same as long as the device is attached to the same port.
+type DeviceInfo struct {
+	ID            string // bus:address, as returned by Device.ID
+	Bus           int
+	Address       int
+	PortPath      string // bus-port.port..., as used by Linux sysfs
+	Speed         Speed
+	Manufacturer  string
+	Product       string
+	SerialNumber  string
+	Version       uint16 // bcdDevice, the firmware revision
+	InPacketSize  int    // maximum packet size of the bulk IN endpoint
+	OutPacketSize int    // maximum packet size of the bulk OUT endpoint
+}
+
+// VersionString returns the firmware revision formatted as major.minor.
+func (i *DeviceInfo) VersionString() string {
+	return fmt.Sprintf("%x.%02x", i.Version>>8, i.Version&0xff)
+}
+
+// Matches reports whether id identifies the device. id may be a bus:address
+// pair as returned by Device.ID, a port path, or a serial number.
+func (i *DeviceInfo) Matches(id string) bool {
+	return id != "" && (id == i.ID || id == i.PortPath || id == i.SerialNumber)
+}
+
+// informer is implemented by transports that can describe the underlying
+// device.
+type informer interface {
+	info() (DeviceInfo, error)
+}
+
+// Info returns information describing the device. If the device is not open,
+// it is opened temporarily to read its string descriptors; the strings are
+// left empty if this is not possible. Info must be called within the context
+// of a Walk if the device is not open, unless the device was returned by List.
+func (d *Device) Info() (DeviceInfo, error) {
+	if t, ok := d.t.(informer); ok {
+		return t.info()
+	}
+	return DeviceInfo{ID: d.ID()}, nil
+}
+
+// Devices returns information describing each supported device attached to
+// the host.
+func (s *Session) Devices() ([]DeviceInfo, error) {
+	var infos []DeviceInfo
+
+	err := s.Walk(func(d *Device) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+		return nil
+	})
+	if err == ErrNoDevices {
+		err = nil
+	}
+	return infos, err
+}
+
+// Devices calls Devices on the default session.
+func Devices() ([]DeviceInfo, error) {
+	s, err := DefaultSession()
+	if err != nil {
+		return nil, err
+	}
+	return s.Devices()
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Size returns the number of addressable bytes, which allows a Device to be
+// used with io.NewSectionReader. If the device is bound to a chip, the
+// capacity of the chip is returned.
+func (d *Device) Size() int64 {
+	if d.chip != nil {
+		return int64(d.chip.Capacity)
+	}
+	return MaxBytes
+}
+
+// ReadAt implements the io.ReaderAt interface. Banks are selected as needed.
+// Reads that extend beyond the end of the device return the available data
+// along with io.EOF.
+func (d *Device) ReadAt(p []byte, off int64) (int, error) {
+	n, err := d.clip(p, off)
+	if n > 0 {
+		if err := d.ReadExtended(uint32(off), p[:n]); err != nil {
+			return 0, err
+		}
+	}
+	if err == errEnd {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// WriteAt implements the io.WriterAt interface. If a page size has been set
+// with SetPageSize or the bound chip supports page writes, WritePages is used;
+// otherwise WriteBytes is used. Partial pages at an unaligned offset are
+// written using WriteBytes, and banks are selected as needed. Writes that
+// extend beyond the end of the device store the data that fits and return
+// io.ErrShortWrite.
+func (d *Device) WriteAt(p []byte, off int64) (int, error) {
+	n, err := d.clip(p, off)
+	if n > 0 {
+		if err := d.WriteExtended(uint32(off), p[:n]); err != nil {
+			return 0, err
+		}
+	}
+	if err == errEnd {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+// write writes data using WritePages if a page size is known; otherwise
+// WriteBytes is used. If the bound chip requires pages to be aligned, partial
+// pages at either end of data are written using WriteBytes.
+func (d *Device) write(ctx context.Context, start uint16, data []byte) error {
+	if d.pageSize() == 0 {
+		return d.WriteBytesContext(ctx, start, data)
+	}
+	if d.chip == nil || d.chip.PageAlign <= 1 {
+		return d.WritePagesContext(ctx, start, data)
+	}
+	align := d.chip.PageAlign
+	head := (align - int(start)%align) % align
+	if head > len(data) {
+		head = len(data)
+	}
+	tail := (len(data) - head) % align
+
+	parts := []struct {
+		n  int
+		op func(context.Context, uint16, []byte) error
+	}{
+		{head, d.WriteBytesContext},
+		{len(data) - head - tail, d.WritePagesContext},
+		{tail, d.WriteBytesContext},
+	}
+	var off int
+	defer d.relativeProgress(&off, len(data))()
+
+	for _, p := range parts {
+		if p.n == 0 {
+			continue
+		}
+		if err := p.op(ctx, start+uint16(off), data[off:off+p.n]); err != nil {
+			var e *InterruptedError
+			if errors.As(err, &e) {
+				e.Start, e.N = uint32(start), off+e.N
+			}
+			return err
+		}
+		off += p.n
+	}
+	return nil
+}
+
+var errEnd = errors.New("end of device")
+
+// clip returns the number of bytes of p that may be transferred starting at
+// off, and errEnd if p extends beyond the end of the device.
+func (d *Device) clip(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	size := d.Size()
+	if off >= size {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, errEnd
+	}
+	if n := size - off; int64(len(p)) > n {
+		return int(n), errEnd
+	}
+	return len(p), nil
+}
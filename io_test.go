@@ -0,0 +1,127 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"io"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestReadAt(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	mem := s.Memory()
+	for i := range mem {
+		mem[i] = byte(i)
+	}
+	tests := []struct {
+		off  int64
+		size int
+		n    int
+		err  error
+	}{
+		{0, 16, 16, nil},
+		{eeprom.MaxBytes - 16, 16, 16, nil},
+		{eeprom.MaxBytes - 8, 16, 8, io.EOF},
+		{eeprom.MaxBytes, 16, 0, io.EOF},
+		{eeprom.MaxBytes, 0, 0, nil},
+	}
+	for _, test := range tests {
+		p := make([]byte, test.size)
+		n, err := d.ReadAt(p, test.off)
+		if n != test.n || err != test.err {
+			t.Fatalf("ReadAt(%d, %d): expected %d, %v; got %d, %v", test.size, test.off, test.n, test.err, n, err)
+		}
+		if !bytes.Equal(p[:n], mem[test.off:test.off+int64(n)]) {
+			t.Fatalf("ReadAt(%d, %d): unexpected data % x", test.size, test.off, p[:n])
+		}
+	}
+	if _, err := d.ReadAt(nil, -1); err == nil {
+		t.Fatal("expected error for negative offset")
+	}
+}
+
+func TestWriteAt(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	p := []byte{0x01, 0x02, 0x03, 0x04}
+	if n, err := d.WriteAt(p, 0x100); n != len(p) || err != nil {
+		t.Fatalf("expected %d, <nil>; got %d, %v", len(p), n, err)
+	}
+	if n, err := d.WriteAt(p, eeprom.MaxBytes-2); n != 2 || err != io.ErrShortWrite {
+		t.Fatalf("expected 2, %v; got %d, %v", io.ErrShortWrite, n, err)
+	}
+	mem := s.Memory()
+	if !bytes.Equal(mem[0x100:0x104], p) || !bytes.Equal(mem[eeprom.MaxBytes-2:], p[:2]) {
+		t.Fatal("unexpected memory contents")
+	}
+}
+
+func TestWriteAtUnaligned(t *testing.T) {
+	tests := []struct {
+		off  int64
+		size int
+	}{
+		{0x101, 4},
+		{0x101, 200},
+		{0x13f, 66},
+		{0x140, 64},
+		{0x7f01, 255},
+	}
+	for _, test := range tests {
+		s := eeprom.NewSimulator()
+		d := eeprom.NewDevice(s)
+		d.SetChip(eeprom.LookupChip("AT28C256"))
+
+		p := make([]byte, test.size)
+		for i := range p {
+			p[i] = byte(i)
+		}
+		if n, err := d.WriteAt(p, test.off); n != len(p) || err != nil {
+			t.Fatalf("WriteAt(%d, %#x): expected %d, <nil>; got %d, %v", test.size, test.off, len(p), n, err)
+		}
+		if !bytes.Equal(s.Memory()[test.off:test.off+int64(len(p))], p) {
+			t.Fatalf("WriteAt(%d, %#x): unexpected memory contents", test.size, test.off)
+		}
+	}
+}
+
+func TestSectionReader(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(d, 0, d.Size())); err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha1.Sum(s.Memory()); !bytes.Equal(h.Sum(nil), sum[:]) {
+		t.Fatal("checksum mismatch")
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+//go:build cgo && !usbfs
+// +build cgo,!usbfs
+
+package eeprom
+
+/*
+#cgo LDFLAGS: -lusb-1.0
+#include <stdlib.h>
+#include <libusb-1.0/libusb.h>
+
+static void LIBUSB_CALL bulkTransferDone(struct libusb_transfer *transfer)
+{
+	*(int *)transfer->user_data = 1;
+}
+
+// submitBulkTransfer submits a bulk transfer; completed is set once the
+// transfer is complete.
+static int submitBulkTransfer(struct libusb_transfer *transfer, libusb_device_handle *handle,
+	unsigned char endpoint, unsigned char *buffer, int length, unsigned int timeout, int *completed)
+{
+	libusb_fill_bulk_transfer(transfer, handle, endpoint, buffer, length,
+		bulkTransferDone, completed, timeout);
+	return libusb_submit_transfer(transfer);
+}
+
+static void setLogLevel(libusb_context *ctx, int level)
+{
+#if LIBUSB_API_VERSION >= 0x01000106
+	libusb_set_option(ctx, LIBUSB_OPTION_LOG_LEVEL, level);
+#else
+	libusb_set_debug(ctx, level);
+#endif
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+func libusbError(code C.int) error {
+	return &USBError{
+		Code: int(code),
+		Msg: fmt.Sprintf("%s (%s)",
+			C.GoString(C.libusb_strerror(C.enum_libusb_error(code))),
+			C.GoString(C.libusb_error_name(code))),
+	}
+}
+
+// usbTransport is a Transport backed by libusb.
+type usbTransport struct {
+	ctx    *C.libusb_context
+	dev    *C.libusb_device
+	handle *C.libusb_device_handle
+	match  Match
+	held   bool // reference held by hold
+}
+
+// hold references the device so that it is not freed when the device list
+// is freed by Walk.
+func (t *usbTransport) hold() {
+	C.libusb_ref_device(t.dev)
+	t.held = true
+}
+
+func (t *usbTransport) ID() string {
+	return fmt.Sprintf("%d:%d",
+		C.libusb_get_bus_number(t.dev),
+		C.libusb_get_device_address(t.dev))
+}
+
+func (t *usbTransport) matched() Match { return t.match }
+
+func (t *usbTransport) info() (DeviceInfo, error) {
+	var desc C.struct_libusb_device_descriptor
+	var ports [7]C.uint8_t
+
+	if err := C.libusb_get_device_descriptor(t.dev, &desc); err != C.LIBUSB_SUCCESS {
+		return DeviceInfo{}, libusbError(err)
+	}
+	info := DeviceInfo{
+		ID:            t.ID(),
+		Bus:           int(C.libusb_get_bus_number(t.dev)),
+		Address:       int(C.libusb_get_device_address(t.dev)),
+		Speed:         Speed(C.libusb_get_device_speed(t.dev)),
+		Version:       uint16(desc.bcdDevice),
+		InPacketSize:  int(C.libusb_get_max_packet_size(t.dev, C.uchar(t.match.in()))),
+		OutPacketSize: int(C.libusb_get_max_packet_size(t.dev, C.uchar(t.match.out()))),
+	}
+	if n := C.libusb_get_port_numbers(t.dev, &ports[0], C.int(len(ports))); n > 0 {
+		info.PortPath = fmt.Sprintf("%d-%d", info.Bus, ports[0])
+		for _, port := range ports[1:n] {
+			info.PortPath += fmt.Sprintf(".%d", port)
+		}
+	}
+
+	handle := t.handle
+	if handle == nil {
+		if err := C.libusb_open(t.dev, &handle); err != C.LIBUSB_SUCCESS {
+			return info, nil
+		}
+		defer C.libusb_close(handle)
+	}
+	info.Manufacturer = stringDescriptor(handle, desc.iManufacturer)
+	info.Product = stringDescriptor(handle, desc.iProduct)
+	info.SerialNumber = stringDescriptor(handle, desc.iSerialNumber)
+	return info, nil
+}
+
+// stringDescriptor returns the string descriptor with the given index, or an
+// empty string if the descriptor cannot be read.
+func stringDescriptor(handle *C.libusb_device_handle, index C.uint8_t) string {
+	var data [256]C.uchar
+
+	if index == 0 {
+		return ""
+	}
+	n := C.libusb_get_string_descriptor_ascii(handle, index, &data[0], C.int(len(data)))
+	if n < 0 {
+		return ""
+	}
+	return C.GoStringN((*C.char)(unsafe.Pointer(&data[0])), n)
+}
+
+func (t *usbTransport) open() error {
+	if err := C.libusb_open(t.dev, &t.handle); err != C.LIBUSB_SUCCESS {
+		return libusbError(err)
+	}
+	if err := C.libusb_claim_interface(t.handle, C.int(t.match.Interface)); err != C.LIBUSB_SUCCESS {
+		C.libusb_close(t.handle)
+		t.handle = nil
+		return libusbError(err)
+	}
+	return nil
+}
+
+func (t *usbTransport) reopen() error {
+	C.libusb_ref_device(t.dev) // keep device alive while closed
+	defer C.libusb_unref_device(t.dev)
+
+	t.close()
+	return t.open()
+}
+
+func (t *usbTransport) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	return t.bulkTransfer(t.match.out(), data, timeout)
+}
+
+func (t *usbTransport) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	return t.bulkTransfer(t.match.in(), data, timeout)
+}
+
+func (t *usbTransport) bulkTransfer(endpoint uint8, data []byte, timeout time.Duration) (int, error) {
+	var transferred C.int
+
+	if err := C.libusb_bulk_transfer(t.handle, C.uchar(endpoint), (*C.uchar)(&data[0]), C.int(len(data)),
+		&transferred, C.uint(timeout/time.Millisecond)); err != C.LIBUSB_SUCCESS {
+		return int(transferred), libusbError(err)
+	}
+	return int(transferred), nil
+}
+
+// usbPending is an asynchronous transfer in flight. Memory referenced by the
+// transfer is allocated by C, as libusb retains it after submission.
+type usbPending struct {
+	ctx       *C.libusb_context
+	transfer  *C.struct_libusb_transfer
+	buffer    unsafe.Pointer // completion flag followed by data
+	completed *C.int
+	data      []byte
+	in        bool
+}
+
+func (t *usbTransport) submit(endpoint uint8, data []byte, timeout time.Duration) (pending, error) {
+	ep := t.match.out()
+	if endpoint == endpointIN {
+		ep = t.match.in()
+	}
+	p := &usbPending{ctx: t.ctx, data: data, in: endpoint == endpointIN}
+	if p.transfer = C.libusb_alloc_transfer(0); p.transfer == nil {
+		return nil, libusbError(C.LIBUSB_ERROR_NO_MEM)
+	}
+	p.buffer = C.calloc(1, C.size_t(C.sizeof_int)+C.size_t(len(data)))
+	if p.buffer == nil {
+		C.libusb_free_transfer(p.transfer)
+		return nil, libusbError(C.LIBUSB_ERROR_NO_MEM)
+	}
+	p.completed = (*C.int)(p.buffer)
+	if !p.in {
+		copy(p.bytes(), data)
+	}
+	if err := C.submitBulkTransfer(p.transfer, t.handle, C.uchar(ep), (*C.uchar)(unsafe.Pointer(&p.bytes()[0])),
+		C.int(len(data)), C.uint(timeout/time.Millisecond), p.completed); err != C.LIBUSB_SUCCESS {
+		p.free()
+		return nil, libusbError(err)
+	}
+	return p, nil
+}
+
+// bytes returns the data buffer of the transfer.
+func (p *usbPending) bytes() []byte {
+	return (*[1 << 30]byte)(unsafe.Pointer(uintptr(p.buffer) + uintptr(C.sizeof_int)))[:len(p.data):len(p.data)]
+}
+
+func (p *usbPending) free() {
+	C.libusb_free_transfer(p.transfer)
+	C.free(p.buffer)
+}
+
+func (p *usbPending) wait() (int, error) {
+	defer p.free()
+
+	for *p.completed == 0 {
+		C.libusb_handle_events_completed(p.ctx, p.completed)
+	}
+	n := int(p.transfer.actual_length)
+	if p.in {
+		copy(p.data, p.bytes()[:n])
+	}
+	switch p.transfer.status {
+	case C.LIBUSB_TRANSFER_COMPLETED:
+		return n, nil
+	case C.LIBUSB_TRANSFER_TIMED_OUT:
+		return n, libusbError(C.LIBUSB_ERROR_TIMEOUT)
+	case C.LIBUSB_TRANSFER_CANCELLED:
+		return n, libusbError(C.LIBUSB_ERROR_INTERRUPTED)
+	case C.LIBUSB_TRANSFER_STALL:
+		return n, libusbError(C.LIBUSB_ERROR_PIPE)
+	case C.LIBUSB_TRANSFER_NO_DEVICE:
+		return n, libusbError(C.LIBUSB_ERROR_NO_DEVICE)
+	case C.LIBUSB_TRANSFER_OVERFLOW:
+		return n, libusbError(C.LIBUSB_ERROR_OVERFLOW)
+	default:
+		return n, libusbError(C.LIBUSB_ERROR_IO)
+	}
+}
+
+func (p *usbPending) cancel() int {
+	C.libusb_cancel_transfer(p.transfer) // fails once complete
+	n, _ := p.wait()
+	return n
+}
+
+func (t *usbTransport) MaxPacketSize() int {
+	return int(C.libusb_get_max_packet_size(t.dev, C.uchar(t.match.out())))
+}
+
+func (t *usbTransport) Reset() error {
+	if err := C.libusb_reset_device(t.handle); err != C.LIBUSB_SUCCESS {
+		return libusbError(err)
+	}
+	return nil
+}
+
+func (t *usbTransport) Close() error {
+	if t.held {
+		defer func() {
+			C.libusb_unref_device(t.dev)
+			t.held = false
+		}()
+	}
+	return t.close()
+}
+
+// close releases the interface and closes the device, if open.
+func (t *usbTransport) close() error {
+	if t.handle == nil {
+		return nil
+	}
+	defer func() {
+		C.libusb_close(t.handle)
+		t.handle = nil
+	}()
+
+	if err := C.libusb_release_interface(t.handle, C.int(t.match.Interface)); err != C.LIBUSB_SUCCESS {
+		return libusbError(err)
+	}
+	return nil
+}
+
+// usbSession holds the libusb context of a Session.
+type usbSession struct {
+	ctx *C.libusb_context
+}
+
+func (s *Session) init() error {
+	if err := C.libusb_init(&s.ctx); err != C.LIBUSB_SUCCESS {
+		return libusbError(err)
+	}
+	return nil
+}
+
+// SetDebug sets the verbosity of messages logged by libusb to standard error.
+func (s *Session) SetDebug(level DebugLevel) {
+	C.setLogLevel(s.ctx, C.int(level))
+}
+
+// Close releases the libusb context. Devices discovered by the session must
+// be closed beforehand. Returned errors may be safely ignored.
+func (s *Session) Close() error {
+	if s.ctx != nil {
+		C.libusb_exit(s.ctx)
+		s.ctx = nil
+	}
+	return nil
+}
+
+// devices implements the bus interface using the libusb device list.
+func (u *usbSession) devices(fn func(vendor, product uint16, transport func(Match) Transport) error) error {
+	var list **C.libusb_device
+
+	n := C.libusb_get_device_list(u.ctx, &list)
+	if n < C.LIBUSB_SUCCESS {
+		return libusbError(C.int(n))
+	}
+	defer C.libusb_free_device_list(list, 1)
+
+	for _, dev := range (*[1 << 20]*C.libusb_device)(unsafe.Pointer(list))[:n:n] {
+		var desc C.struct_libusb_device_descriptor
+
+		if err := C.libusb_get_device_descriptor(dev, &desc); err != C.LIBUSB_SUCCESS {
+			return libusbError(err)
+		}
+		err := fn(uint16(desc.idVendor), uint16(desc.idProduct), func(m Match) Transport {
+			return &usbTransport{ctx: u.ctx, dev: dev, match: m}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+// Match identifies devices that conform to the programmer protocol. The
+// matches set for a Session are used by its Walk, First, List, Watch and
+// Devices methods to recognize supported devices.
+type Match struct {
+	Vendor    uint16 // idVendor
+	Product   uint16 // idProduct
+	Interface int    // interface number
+	Endpoint  int    // bulk endpoint number; zero selects endpoint 1
+}
+
+// DefaultMatch matches the reference programmer.
+var DefaultMatch = Match{Vendor: idVendor, Product: idProduct, Interface: interfaceNum}
+
+// SetMatches sets the devices recognized as supported by the session. If no
+// matches are given, DefaultMatch is used.
+func (s *Session) SetMatches(m ...Match) {
+	s.matchesMu.Lock()
+	defer s.matchesMu.Unlock()
+
+	s.matches = append([]Match(nil), m...)
+}
+
+// SetMatches calls SetMatches on the default session.
+func SetMatches(m ...Match) error {
+	s, err := DefaultSession()
+	if err != nil {
+		return err
+	}
+	s.SetMatches(m...)
+	return nil
+}
+
+// lookupMatch returns the first match for the given vendor and product IDs.
+func (s *Session) lookupMatch(vendor, product uint16) (Match, bool) {
+	s.matchesMu.RLock()
+	defer s.matchesMu.RUnlock()
+
+	matches := s.matches
+	if len(matches) == 0 {
+		matches = []Match{DefaultMatch}
+	}
+	for _, m := range matches {
+		if m.Vendor == vendor && m.Product == product {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+// matcher is implemented by transports for devices recognized by a Match.
+type matcher interface {
+	matched() Match
+}
+
+func (m Match) endpoint() uint8 {
+	if m.Endpoint == 0 {
+		return endpointNum
+	}
+	return uint8(m.Endpoint)
+}
+
+// in returns the address of the bulk IN endpoint.
+func (m Match) in() uint8 { return m.endpoint() | 0x80 }
+
+// out returns the address of the bulk OUT endpoint.
+func (m Match) out() uint8 { return m.endpoint() }
@@ -0,0 +1,199 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	pcapMagic        = 0xa1b2c3d4
+	pcapSnapLen      = 65535
+	linkTypeUSBLinux = 189 // LINKTYPE_USB_LINUX
+)
+
+// Linux errno values used in URB status; these do not depend on the host.
+const (
+	linuxENOENT      = 2
+	linuxEIO         = 5
+	linuxENODEV      = 19
+	linuxEPIPE       = 32
+	linuxEOVERFLOW   = 75
+	linuxEINPROGRESS = 115
+)
+
+// usbmonPacket is the header preceding each packet captured by usbmon; see
+// struct usbmon_packet in Documentation/usb/usbmon.rst.
+type usbmonPacket struct {
+	ID        uint64
+	Type      byte // 'S' for submission, 'C' for completion
+	XferType  byte
+	Endpoint  byte
+	Device    byte
+	Bus       uint16
+	FlagSetup byte
+	FlagData  byte
+	TsSec     int64
+	TsUsec    int32
+	Status    int32
+	Length    uint32
+	LenCap    uint32
+	Setup     [8]byte
+}
+
+const usbmonXferBulk = 3
+
+// PcapWriter writes bulk transfers to a pcap file using the Linux usbmon link
+// type, which may be opened by Wireshark. Each transfer is written as a
+// submission followed by a completion once the transfer is complete.
+type PcapWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	id  uint64 // ID of the last URB written
+	err error
+}
+
+// NewPcapWriter writes a pcap file header to w and returns a PcapWriter.
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	hdr := struct {
+		Magic        uint32
+		Major, Minor uint16
+		ThisZone     int32
+		SigFigs      uint32
+		SnapLen      uint32
+		Network      uint32
+	}{pcapMagic, 2, 4, 0, 0, pcapSnapLen, linkTypeUSBLinux}
+	if err := binary.Write(w, binary.LittleEndian, hdr); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// Tracer returns a Tracer that writes the bulk transfers of a device with the
+// given bus number and address. Tracers returned by the same PcapWriter may be
+// used concurrently.
+func (p *PcapWriter) Tracer(bus, address int) Tracer {
+	return &pcapTracer{p, uint16(bus), uint8(address)}
+}
+
+// Err returns the first error encountered writing the file.
+func (p *PcapWriter) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err
+}
+
+func (p *PcapWriter) transfer(bus uint16, device uint8, t TransferTrace) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return
+	}
+	p.id++
+	submit := usbmonPacket{
+		ID:        p.id,
+		Type:      'S',
+		XferType:  usbmonXferBulk,
+		Endpoint:  t.Endpoint,
+		Device:    device,
+		Bus:       bus,
+		FlagSetup: '-',
+		Status:    -linuxEINPROGRESS,
+		Length:    uint32(t.Length),
+	}
+	complete := submit
+	complete.Type = 'C'
+	complete.Status = urbStatus(t.Err)
+	complete.Length = uint32(t.Transferred)
+
+	var in, out []byte
+	if t.Endpoint&0x80 != 0 {
+		submit.FlagData = '<'
+		in = t.Data
+	} else {
+		complete.FlagData = '>'
+		out = t.Data
+	}
+	if p.err = p.packet(t.Start, submit, out); p.err == nil {
+		p.err = p.packet(t.Start.Add(t.Duration), complete, in)
+	}
+}
+
+// packet writes a pcap record containing a usbmon packet.
+func (p *PcapWriter) packet(ts time.Time, hdr usbmonPacket, data []byte) error {
+	var b bytes.Buffer
+
+	hdr.TsSec, hdr.TsUsec = ts.Unix(), int32(ts.Nanosecond()/1000)
+	hdr.LenCap = uint32(len(data))
+	n := uint32(binary.Size(hdr) + len(data))
+	binary.Write(&b, binary.LittleEndian, []uint32{uint32(ts.Unix()), uint32(hdr.TsUsec), n, n})
+	binary.Write(&b, binary.LittleEndian, hdr)
+	b.Write(data)
+
+	_, err := p.w.Write(b.Bytes())
+	return err
+}
+
+// urbStatus returns the status of a URB that completed with err. Transfers
+// that time out are unlinked by the host, and are reported as such.
+func urbStatus(err error) int32 {
+	if err == nil {
+		return 0
+	}
+	if err == errCancelled || errors.Is(err, ErrTimeout) {
+		return -linuxENOENT
+	}
+	var e *USBError
+	if errors.As(err, &e) {
+		switch e.Code {
+		case codeInterrupted:
+			return -linuxENOENT
+		case codeNoDevice:
+			return -linuxENODEV
+		case codePipe:
+			return -linuxEPIPE
+		case codeOverflow:
+			return -linuxEOVERFLOW
+		}
+	}
+	return -linuxEIO
+}
+
+// pcapTracer passes the bulk transfers of a device to a PcapWriter.
+type pcapTracer struct {
+	p      *PcapWriter
+	bus    uint16
+	device uint8
+}
+
+func (t *pcapTracer) Command(CommandTrace)     {}
+func (t *pcapTracer) Transfer(x TransferTrace) { t.p.transfer(t.bus, t.device, x) }
+func (t *pcapTracer) Status(StatusTrace)       {}
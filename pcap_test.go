@@ -0,0 +1,94 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestPcapWriter(t *testing.T) {
+	var b bytes.Buffer
+
+	p, err := eeprom.NewPcapWriter(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	d.SetTracer(p.Tracer(1, 5))
+	if err := d.Read(0x100, make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var hdr [6]uint32
+	binary.Read(&b, binary.LittleEndian, &hdr)
+	if hdr[0] != 0xa1b2c3d4 || hdr[5] != 189 {
+		t.Fatalf("unexpected file header %x", hdr)
+	}
+
+	// Command, two data packets and status, each submitted and completed.
+	want := []struct {
+		typ      byte
+		endpoint byte
+		status   int32
+		length   uint32
+		data     int
+	}{
+		{'S', 0x01, -115, 5, 5},
+		{'C', 0x01, 0, 5, 0},
+		{'S', 0x81, -115, 64, 0},
+		{'C', 0x81, 0, 64, 64},
+		{'S', 0x81, -115, 36, 0},
+		{'C', 0x81, 0, 36, 36},
+		{'S', 0x81, -115, 2, 0},
+		{'C', 0x81, 0, 2, 2},
+	}
+	for i, w := range want {
+		var rec [4]uint32
+		var pkt [48]byte
+		binary.Read(&b, binary.LittleEndian, &rec)
+		b.Read(pkt[:])
+		data := b.Next(int(rec[2]) - len(pkt))
+
+		typ, endpoint := pkt[8], pkt[10]
+		status := int32(binary.LittleEndian.Uint32(pkt[28:]))
+		length := binary.LittleEndian.Uint32(pkt[32:])
+		if typ != w.typ || endpoint != w.endpoint || status != w.status || length != w.length || len(data) != w.data {
+			t.Errorf("packet %d: expected %c %#x status %d length %d with %d bytes; got %c %#x status %d length %d with %d bytes",
+				i, w.typ, w.endpoint, w.status, w.length, w.data, typ, endpoint, status, length, len(data))
+		}
+		if pkt[11] != 5 || binary.LittleEndian.Uint16(pkt[12:]) != 1 {
+			t.Errorf("packet %d: expected device 1:5", i)
+		}
+	}
+	if b.Len() != 0 {
+		t.Errorf("unexpected %d trailing bytes", b.Len())
+	}
+}
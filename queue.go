@@ -0,0 +1,148 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+const defaultQueueDepth = 8
+
+// queuer is implemented by transports that can keep several transfers in
+// flight, which avoids leaving the bus idle between packets.
+type queuer interface {
+	// submit starts a transfer of data to or from the given endpoint and
+	// returns the transfer in flight. The transfer owns data until it is
+	// complete. Transfers to the same endpoint complete in the order they
+	// were submitted.
+	submit(endpoint uint8, data []byte, timeout time.Duration) (pending, error)
+}
+
+// pending is a transfer in flight.
+type pending interface {
+	// wait waits for the transfer to complete and returns the number of
+	// bytes transferred.
+	wait() (int, error)
+
+	// cancel cancels the transfer, waits for it to complete, and returns
+	// the number of bytes transferred before it was cancelled.
+	cancel() int
+}
+
+// queued is a packet of a queued transfer.
+type queued struct {
+	pending
+	off, n int // offset and length of the packet
+	start  time.Time
+}
+
+// SetQueueDepth sets the maximum number of packets kept in flight by Read and
+// WritePages if the transport supports queued transfers. A depth of one
+// transfers each packet synchronously. The default is 8.
+func (d *Device) SetQueueDepth(depth int) { d.depth = depth }
+
+func (d *Device) queueDepth() int {
+	if d.depth == 0 {
+		return defaultQueueDepth
+	}
+	return d.depth
+}
+
+// transferQueued is like transferN, but keeps up to queueDepth packets in
+// flight. As each packet waits for those ahead of it, its timeout is scaled
+// by its position in the queue. If a packet is short, packets in flight
+// behind it are cancelled and the transfer continues from the end of the
+// short packet.
+func (d *Device) transferQueued(ctx context.Context, q queuer, endpoint uint8, data []byte, n int, base time.Duration, progress func(int, int)) (int, error) {
+	var queue []queued
+	var off, next int // bytes completed and submitted
+
+	// cancel cancels the packets in flight. Data received by cancelled
+	// packets follows the data completed so far, and is moved into place.
+	cancel := func() error {
+		var err error
+		for _, p := range queue {
+			m := p.cancel()
+			d.traceTransfer(endpoint, data[p.off:p.off+p.n], m, p.start, errCancelled)
+			if m > 0 && p.off != off {
+				if endpoint != endpointIN {
+					err = io.ErrShortWrite // data sent out of order
+				}
+				copy(data[off:], data[p.off:p.off+m])
+			}
+			off += m
+		}
+		queue = queue[:0]
+		next = off
+		return err
+	}
+
+	for depth := d.queueDepth(); off < len(data); {
+		for len(queue) < depth && next < len(data) && ctx.Err() == nil {
+			m := n
+			if m > len(data)-next {
+				m = len(data) - next
+			}
+			start := time.Now()
+			t := time.Duration(len(queue)+1) * d.timeouts.scale(base, m)
+			p, err := q.submit(endpoint, data[next:next+m], timeout(ctx, t))
+			if err != nil {
+				d.traceTransfer(endpoint, data[next:next+m], 0, start, err)
+				cancel()
+				return off, err
+			}
+			queue = append(queue, queued{p, next, m, start})
+			next += m
+		}
+		if err := ctx.Err(); err != nil {
+			cancel()
+			return off, err
+		}
+
+		p := queue[0]
+		queue = queue[1:]
+		transferred, err := p.wait()
+		d.traceTransfer(endpoint, data[p.off:p.off+p.n], transferred, p.start, err)
+		off += transferred
+		if err != nil {
+			cancel()
+			return off, err
+		}
+		if transferred == 0 {
+			cancel()
+			return off, io.ErrNoProgress
+		}
+		if transferred < p.n {
+			if err := cancel(); err != nil {
+				return off, err
+			}
+		}
+		if progress != nil {
+			progress(off, len(data))
+		}
+	}
+	return len(data), nil
+}
@@ -0,0 +1,193 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// queuedSimulator is a Simulator that supports queued transfers. Packets are
+// transferred when waited on, in the order they were submitted.
+type queuedSimulator struct {
+	*Simulator
+	queue    []*simPending
+	inFlight int             // maximum number of packets in flight
+	timeouts []time.Duration // timeout of each packet submitted
+}
+
+type simPending struct {
+	s        *queuedSimulator
+	endpoint uint8
+	data     []byte
+	done     bool
+}
+
+func (s *queuedSimulator) submit(endpoint uint8, data []byte, timeout time.Duration) (pending, error) {
+	p := &simPending{s: s, endpoint: endpoint, data: data}
+	s.queue = append(s.queue, p)
+	s.timeouts = append(s.timeouts, timeout)
+	if len(s.queue) > s.inFlight {
+		s.inFlight = len(s.queue)
+	}
+	return p, nil
+}
+
+func (p *simPending) wait() (int, error) {
+	if p.s.queue[0] != p {
+		panic("transfer completed out of order")
+	}
+	p.s.queue = p.s.queue[1:]
+	if p.endpoint == endpointIN {
+		return p.s.BulkIn(p.data, 0)
+	}
+	return p.s.BulkOut(p.data, 0)
+}
+
+func (p *simPending) cancel() int {
+	for i, q := range p.s.queue {
+		if q == p {
+			p.s.queue = append(p.s.queue[:i], p.s.queue[i+1:]...)
+			break
+		}
+	}
+	return 0
+}
+
+func TestTransferQueued(t *testing.T) {
+	tests := []struct {
+		name   string
+		depth  int
+		faults Faults
+		want   int
+	}{
+		{"Default", 0, Faults{}, defaultQueueDepth},
+		{"Depth", 3, Faults{}, 3},
+		{"Sync", 1, Faults{}, 0},
+		{"Short", 0, Faults{MaxTransfer: 40}, defaultQueueDepth},
+	}
+	for _, test := range tests {
+		s := &queuedSimulator{Simulator: NewSimulator()}
+		d := NewDevice(s)
+		d.SetQueueDepth(test.depth)
+		d.SetPageSize(32)
+
+		data := make([]byte, 8192)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		s.SetFaults(test.faults)
+		if err := d.WritePages(0x100, data); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		buf := make([]byte, len(data))
+		if err := d.Read(0x100, buf); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.Equal(buf, data) {
+			t.Errorf("%s: unexpected data", test.name)
+		}
+		if s.inFlight != test.want {
+			t.Errorf("%s: expected %d packets in flight; got %d", test.name, test.want, s.inFlight)
+		}
+	}
+}
+
+func TestTransferQueuedTimeouts(t *testing.T) {
+	s := &queuedSimulator{Simulator: NewSimulator()}
+	d := NewDevice(s)
+	d.SetQueueDepth(4)
+	d.SetTimeouts(Timeouts{Transfer: time.Second})
+
+	// Each packet is allowed a timeout for every packet ahead of it.
+	if err := d.Read(0, make([]byte, 6*simPacketSize)); err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{1, 2, 3, 4, 4, 4}
+	if len(s.timeouts) != len(want) {
+		t.Fatalf("expected %d packets; got %d", len(want), len(s.timeouts))
+	}
+	for i, n := range want {
+		if s.timeouts[i] != n*time.Second {
+			t.Fatalf("packet %d: expected timeout %v; got %v", i, n*time.Second, s.timeouts[i])
+		}
+	}
+
+	// Byte writes are not queued, as each byte may take a write cycle.
+	s.inFlight = 0
+	if err := d.WriteBytes(0, make([]byte, 4*simPacketSize)); err != nil {
+		t.Fatal(err)
+	}
+	if s.inFlight != 0 {
+		t.Fatalf("expected no packets in flight; got %d", s.inFlight)
+	}
+}
+
+func TestRecordQueued(t *testing.T) {
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	write := func(d *Device) error {
+		if err := d.WritePages(0x100, data); err != nil {
+			return err
+		}
+		return d.Read(0x100, make([]byte, len(data)))
+	}
+
+	var b bytes.Buffer
+	s := &queuedSimulator{Simulator: NewSimulator()}
+	s.SetFaults(Faults{MaxTransfer: 40})
+	d := NewDevice(s)
+	d.SetPageSize(32)
+	r := d.Record(&b)
+	if err := write(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if s.inFlight != defaultQueueDepth {
+		t.Errorf("expected %d packets in flight; got %d", defaultQueueDepth, s.inFlight)
+	}
+
+	// Short packets cancel the packets queued behind them, which must be
+	// replayed in the same way.
+	if !bytes.Contains(b.Bytes(), []byte(`"cancelled":true`)) {
+		t.Fatal("expected cancelled transfers to be recorded")
+	}
+	p, err := NewReplayer(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = NewDevice(p)
+	d.SetPageSize(32)
+	if err := write(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Done(); err != nil {
+		t.Fatal(err)
+	}
+}
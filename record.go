@@ -0,0 +1,381 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Recordings are written as a sequence of JSON objects, one per line. The
+// first describes the transport; each subsequent object describes a bulk
+// transfer or reset in the order it completed.
+type record struct {
+	Op        string         `json:"op"`             // "transport", "out", "in" or "reset"
+	Len       int            `json:"len,omitempty"`  // bytes requested, or the maximum packet size
+	N         int            `json:"n,omitempty"`    // bytes transferred
+	Data      []byte         `json:"data,omitempty"` // data sent (out) or received (in)
+	Err       *recordedError `json:"err,omitempty"`
+	Cancelled bool           `json:"cancelled,omitempty"` // queued transfer was cancelled
+}
+
+// recordedError is an error returned by a recorded transport.
+type recordedError struct {
+	Code int    `json:"code,omitempty"` // USBError code, if any
+	Msg  string `json:"msg"`
+}
+
+func newRecordedError(err error) *recordedError {
+	if err == nil {
+		return nil
+	}
+	var e *USBError
+	if errors.As(err, &e) {
+		return &recordedError{Code: e.Code, Msg: e.Msg}
+	}
+	if errors.Is(err, ErrTimeout) {
+		return &recordedError{Code: codeTimeout, Msg: err.Error()}
+	}
+	return &recordedError{Msg: err.Error()}
+}
+
+func (e *recordedError) err() error {
+	switch {
+	case e == nil:
+		return nil
+	case e.Code != 0:
+		return &USBError{Code: e.Code, Msg: e.Msg}
+	}
+	return errors.New(e.Msg)
+}
+
+// Recorder is a Transport that records the traffic exchanged with another
+// Transport. Recordings may be replayed using a Replayer.
+type Recorder struct {
+	t   Transport
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder that writes the traffic exchanged with t to
+// w.
+func NewRecorder(t Transport, w io.Writer) *Recorder {
+	r := &Recorder{t: t, enc: json.NewEncoder(w)}
+	r.write(record{Op: "transport", Len: t.MaxPacketSize()})
+	return r
+}
+
+// Record arranges for the traffic exchanged with the device to be written to
+// w, and returns the Recorder. Transfers are queued as they would be if the
+// traffic were not recorded.
+func (d *Device) Record(w io.Writer) *Recorder {
+	r := NewRecorder(d.t, w)
+	if _, ok := d.t.(queuer); ok {
+		d.t = queuedRecorder{r}
+	} else {
+		d.t = r
+	}
+	return r
+}
+
+// Err returns the first error encountered writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+func (r *Recorder) write(rec record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = r.enc.Encode(rec)
+	}
+}
+
+// BulkOut records data transferred to the bulk OUT endpoint.
+func (r *Recorder) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	n, err := r.t.BulkOut(data, timeout)
+	r.write(record{Op: "out", Len: len(data), N: n, Data: data, Err: newRecordedError(err)})
+	return n, err
+}
+
+// BulkIn records data transferred from the bulk IN endpoint.
+func (r *Recorder) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	n, err := r.t.BulkIn(data, timeout)
+	r.write(record{Op: "in", Len: len(data), N: n, Data: data[:n], Err: newRecordedError(err)})
+	return n, err
+}
+
+// MaxPacketSize returns the maximum packet size of the recorded transport.
+func (r *Recorder) MaxPacketSize() int { return r.t.MaxPacketSize() }
+
+// Reset records a device reset.
+func (r *Recorder) Reset() error {
+	err := r.t.Reset()
+	r.write(record{Op: "reset", Err: newRecordedError(err)})
+	return err
+}
+
+// Close closes the recorded transport. The writer is not closed.
+func (r *Recorder) Close() error { return r.t.Close() }
+
+func (r *Recorder) open() error {
+	if t, ok := r.t.(opener); ok {
+		return t.open()
+	}
+	return nil
+}
+
+func (r *Recorder) reopen() error {
+	if t, ok := r.t.(reopener); ok {
+		return t.reopen()
+	}
+	return nil
+}
+
+func (r *Recorder) ID() string {
+	if t, ok := r.t.(identifier); ok {
+		return t.ID()
+	}
+	return ""
+}
+
+func (r *Recorder) info() (DeviceInfo, error) {
+	if t, ok := r.t.(informer); ok {
+		return t.info()
+	}
+	return DeviceInfo{ID: r.ID()}, nil
+}
+
+func (r *Recorder) matched() Match {
+	if t, ok := r.t.(matcher); ok {
+		return t.matched()
+	}
+	return Match{}
+}
+
+func (r *Recorder) hold() {
+	if t, ok := r.t.(holder); ok {
+		t.hold()
+	}
+}
+
+// queuedRecorder is a Recorder for a transport that queues transfers.
+// Queued transfers are recorded as they complete.
+type queuedRecorder struct {
+	*Recorder
+}
+
+func (r queuedRecorder) submit(endpoint uint8, data []byte, timeout time.Duration) (pending, error) {
+	op := "out"
+	if endpoint == endpointIN {
+		op = "in"
+	}
+	p, err := r.t.(queuer).submit(endpoint, data, timeout)
+	if err != nil {
+		r.write(record{Op: op, Len: len(data), Err: newRecordedError(err)})
+		return nil, err
+	}
+	return &recordedPending{r.Recorder, p, op, data}, nil
+}
+
+// recordedPending records a queued transfer once it completes.
+type recordedPending struct {
+	r    *Recorder
+	p    pending
+	op   string
+	data []byte
+}
+
+func (p *recordedPending) wait() (int, error) {
+	n, err := p.p.wait()
+	p.record(n, err, false)
+	return n, err
+}
+
+func (p *recordedPending) cancel() int {
+	n := p.p.cancel()
+	p.record(n, nil, true)
+	return n
+}
+
+func (p *recordedPending) record(n int, err error, cancelled bool) {
+	rec := record{Op: p.op, Len: len(p.data), N: n, Data: p.data, Err: newRecordedError(err), Cancelled: cancelled}
+	if p.op == "in" {
+		rec.Data = p.data[:n]
+	}
+	p.r.write(rec)
+}
+
+// Replayer is a Transport that replays a recording made by a Recorder. Data
+// sent to the device must match the recording exactly; once a request
+// diverges, every subsequent request fails with the same *DivergenceError.
+type Replayer struct {
+	records    []record
+	next       int
+	packetSize int
+	err        error
+}
+
+// NewReplayer returns a Replayer for the recording read from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	var p Replayer
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var rec record
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		p.records = append(p.records, rec)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.records) == 0 || p.records[0].Op != "transport" {
+		return nil, errors.New("invalid recording")
+	}
+	p.packetSize = p.records[0].Len
+	p.next = 1
+	return &p, nil
+}
+
+// describe returns a description of a request for a DivergenceError.
+func describe(op string, n int, data []byte) string {
+	if op == "out" {
+		return fmt.Sprintf("out [% x]", data)
+	}
+	if op == "in" {
+		return fmt.Sprintf("in %d bytes", n)
+	}
+	return op
+}
+
+// replay returns the next record if it matches the given request.
+func (p *Replayer) replay(op string, n int, data []byte) (*record, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	got := describe(op, n, data)
+	if p.next == len(p.records) {
+		p.err = &DivergenceError{p.next, "end of recording", got}
+		return nil, p.err
+	}
+	rec := &p.records[p.next]
+	if rec.Op != op || rec.Len != n || op == "out" && !bytes.Equal(rec.Data, data) {
+		p.err = &DivergenceError{p.next, describe(rec.Op, rec.Len, rec.Data), got}
+		return nil, p.err
+	}
+	p.next++
+	return rec, nil
+}
+
+// BulkOut checks data against the recording and returns the recorded result.
+func (p *Replayer) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	rec, err := p.replay("out", len(data), data)
+	if err != nil {
+		return 0, err
+	}
+	return rec.N, rec.Err.err()
+}
+
+// BulkIn returns the recorded data and result.
+func (p *Replayer) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	rec, err := p.replay("in", len(data), nil)
+	if err != nil {
+		return 0, err
+	}
+	return copy(data, rec.Data), rec.Err.err()
+}
+
+// MaxPacketSize returns the maximum packet size of the recorded transport.
+func (p *Replayer) MaxPacketSize() int { return p.packetSize }
+
+// Reset checks that a reset was recorded and returns the recorded result.
+func (p *Replayer) Reset() error {
+	rec, err := p.replay("reset", 0, nil)
+	if err != nil {
+		return err
+	}
+	return rec.Err.err()
+}
+
+// Close is a no-op.
+func (p *Replayer) Close() error { return nil }
+
+// submit returns a transfer that is replayed once it is waited on, which
+// allows recordings to be replayed whether or not transfers were queued when
+// they were made.
+func (p *Replayer) submit(endpoint uint8, data []byte, timeout time.Duration) (pending, error) {
+	return &replayedPending{p, endpoint, data}, nil
+}
+
+// replayedPending is a queued transfer replayed by a Replayer.
+type replayedPending struct {
+	p        *Replayer
+	endpoint uint8
+	data     []byte
+}
+
+func (q *replayedPending) wait() (int, error) {
+	if q.endpoint == endpointIN {
+		return q.p.BulkIn(q.data, 0)
+	}
+	return q.p.BulkOut(q.data, 0)
+}
+
+// cancel replays a cancelled transfer. Transfers that were not recorded as
+// cancelled were never issued, and are not replayed.
+func (q *replayedPending) cancel() int {
+	p := q.p
+	if p.err != nil || p.next == len(p.records) || !p.records[p.next].Cancelled {
+		return 0
+	}
+	n, _ := q.wait()
+	return n
+}
+
+// Done returns an error if the recording has not been replayed in its
+// entirety, or if the replay diverged.
+func (p *Replayer) Done() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.next < len(p.records) {
+		rec := p.records[p.next]
+		return &DivergenceError{p.next, describe(rec.Op, rec.Len, rec.Data), "end of replay"}
+	}
+	return nil
+}
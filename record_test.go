@@ -0,0 +1,110 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+// recordSession records a session that writes data at 0x3f00 using a
+// simulator with the given faults.
+func recordSession(t *testing.T, f eeprom.Faults, data []byte) (*bytes.Buffer, error) {
+	var b bytes.Buffer
+
+	s := eeprom.NewSimulator()
+	s.SetFaults(f)
+	d := eeprom.NewDevice(s)
+	r := d.Record(&b)
+	err := d.WriteBytes(0x3f00, data)
+	if err == nil {
+		err = d.Read(0x3f00, make([]byte, len(data)))
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return &b, err
+}
+
+func TestReplay(t *testing.T) {
+	data := bytes.Repeat([]byte{0x5a}, 200)
+	tests := []struct {
+		name   string
+		faults eeprom.Faults
+	}{
+		{"Success", eeprom.Faults{}},
+		{"Timeout", eeprom.Faults{Err: eeprom.ErrTimeout, ErrAfter: 100}},
+		{"Status", eeprom.Faults{StatusMask: 0x8000}},
+	}
+	for _, test := range tests {
+		b, want := recordSession(t, test.faults, data)
+
+		p, err := eeprom.NewReplayer(b)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		d := eeprom.NewDevice(p)
+		err = d.WriteBytes(0x3f00, data)
+		if err == nil {
+			err = d.Read(0x3f00, make([]byte, len(data)))
+		}
+		if (err == nil) != (want == nil) || err != nil && err.Error() != want.Error() {
+			t.Errorf("%s: expected %v; got %v", test.name, want, err)
+		}
+		if test.name == "Timeout" && !errors.Is(err, eeprom.ErrTimeout) {
+			t.Errorf("%s: expected %v; got %v", test.name, eeprom.ErrTimeout, err)
+		}
+		if err := p.Done(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestReplayDiverged(t *testing.T) {
+	data := bytes.Repeat([]byte{0x5a}, 200)
+	b, err := recordSession(t, eeprom.Faults{}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := eeprom.NewReplayer(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := eeprom.NewDevice(p)
+	data[100] = 0xa5
+
+	var e *eeprom.DivergenceError
+	if err := d.WriteBytes(0x3f00, data); !errors.As(err, &e) {
+		t.Fatalf("expected *DivergenceError; got %v", err)
+	}
+	if e.Index != 3 {
+		t.Errorf("expected divergence at record 3; got %d", e.Index)
+	}
+	if err := d.Read(0x3f00, data); !errors.As(err, &e) {
+		t.Fatalf("expected *DivergenceError; got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const defaultChunkSize = 4096
+
+// RetryPolicy describes how failed operations are retried. When a transfer
+// fails or the device reports an unexpected status, the device is reset and
+// re-opened, and the operation resumes from the last address confirmed by
+// the device.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts made to transfer each
+	// chunk, including the first. Values less than two disable retries.
+	Attempts int
+
+	// Backoff is the delay before the first retry of a chunk. The delay
+	// is doubled after each subsequent retry.
+	Backoff time.Duration
+
+	// ChunkSize is the number of bytes transferred per command. Progress
+	// is confirmed by the device after each chunk, which bounds the amount
+	// of data that must be transferred again after a failure. The default
+	// is 4096 bytes.
+	ChunkSize int
+}
+
+// reopener is implemented by transports that must be re-opened after a
+// reset.
+type reopener interface {
+	reopen() error
+}
+
+// SetRetryPolicy sets the policy used to retry failed operations. A nil
+// policy, which is the default, disables retries.
+func (d *Device) SetRetryPolicy(p *RetryPolicy) { d.retry = p }
+
+// do performs op on data starting at start. If a retry policy is set, data is
+// transferred in chunks aligned to align bytes, and failed chunks are retried
+// as described by the policy.
+func (d *Device) do(ctx context.Context, start uint16, data []byte, align int,
+	op func(context.Context, uint16, []byte) error) error {
+	// Select the bank before each attempt, as resetting the device
+	// deselects it.
+	fn := op
+	op = func(ctx context.Context, start uint16, data []byte) error {
+		if err := d.selectBank(ctx); err != nil {
+			return interrupted(ctx, start, 0, err)
+		}
+		return fn(ctx, start, data)
+	}
+
+	if d.retry == nil || d.retry.Attempts < 2 {
+		return op(ctx, start, data)
+	}
+	if err := d.validate(start, data); err != nil {
+		return err
+	}
+
+	chunk := d.retry.ChunkSize
+	if chunk <= 0 {
+		chunk = defaultChunkSize
+	}
+	if chunk > align {
+		chunk -= chunk % align
+	}
+
+	var off int
+	defer d.relativeProgress(&off, len(data))()
+
+	for off < len(data) {
+		n := chunk
+		if n > len(data)-off {
+			n = len(data) - off
+		}
+		err := d.withRetry(ctx, func() error {
+			err := op(ctx, start+uint16(off), data[off:off+n])
+
+			var e *StatusError
+			if errors.As(err, &e) {
+				// Resume from the last address confirmed by
+				// the device.
+				m := int(e.Got) - int(start) - off
+				if m -= m % align; m > 0 && m < n {
+					off += m
+					n -= m
+				}
+			}
+			return err
+		})
+		if err != nil {
+			var e *InterruptedError
+			if errors.As(err, &e) {
+				e.Start, e.N = uint32(start), off+e.N
+			}
+			return err
+		}
+		off += n
+	}
+	return nil
+}
+
+// withRetry calls fn, resetting the device and calling fn again after a
+// failure as permitted by the retry policy.
+func (d *Device) withRetry(ctx context.Context, fn func() error) error {
+	if d.retry == nil || d.retry.Attempts < 2 {
+		return fn()
+	}
+	backoff := d.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == d.retry.Attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+
+		if err := d.recover(); err != nil {
+			return err
+		}
+	}
+}
+
+// recover resets the device and re-opens it if required by the transport.
+func (d *Device) recover() error {
+	d.Reset()
+	if t, ok := d.t.(reopener); ok {
+		return t.reopen()
+	}
+	return nil
+}
+
+// retryable reports whether an operation that failed with err may succeed if
+// attempted again.
+func retryable(err error) bool {
+	switch err {
+	case ErrNoData, ErrTooMuchData, ErrPacketSize, ErrUnaligned:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+// countingTransport counts the number of bytes transferred to the device.
+type countingTransport struct {
+	*eeprom.Simulator
+	n int
+}
+
+func (t *countingTransport) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	n, err := t.Simulator.BulkOut(data, timeout)
+	t.n += n
+	return n, err
+}
+
+func TestRetry(t *testing.T) {
+	s := eeprom.NewSimulator()
+	c := &countingTransport{Simulator: s}
+	d := eeprom.NewDevice(c)
+	d.SetRetryPolicy(&eeprom.RetryPolicy{Attempts: 2, ChunkSize: 4096})
+	s.SetFaults(eeprom.Faults{Err: eeprom.ErrTimeout, ErrAfter: 6000})
+
+	data := make([]byte, 16384)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := d.WriteBytes(0, data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Memory()[:len(data)], data) {
+		t.Fatal("unexpected memory contents")
+	}
+	// Only the failed chunk should be transferred again.
+	if c.n >= len(data)+4096+5*5 {
+		t.Fatalf("expected less than %d bytes transferred; got %d", len(data)+4096+5*5, c.n)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetRetryPolicy(&eeprom.RetryPolicy{Attempts: 2})
+	s.SetFaults(eeprom.Faults{StatusMask: 0x8000})
+
+	var e *eeprom.StatusError
+	if err := d.WriteBytes(0, make([]byte, 16)); !errors.As(err, &e) {
+		t.Fatalf("expected *StatusError; got %v", err)
+	}
+}
+
+func TestRetryProgress(t *testing.T) {
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	d.SetRetryPolicy(&eeprom.RetryPolicy{Attempts: 2, ChunkSize: 1024})
+
+	var last int
+	d.SetProgress(func(n, total int) {
+		if total != 4096 || n <= last {
+			t.Fatalf("unexpected progress %d/%d after %d", n, total, last)
+		}
+		last = n
+	})
+	if err := d.Read(0, make([]byte, 4096)); err != nil {
+		t.Fatal(err)
+	}
+	if last != 4096 {
+		t.Fatalf("expected progress to end at 4096; got %d", last)
+	}
+}
+
+// commandRecorder records the starting address of each command.
+type commandRecorder struct {
+	starts []uint16
+}
+
+func (r *commandRecorder) Command(c eeprom.CommandTrace) { r.starts = append(r.starts, c.Start) }
+func (r *commandRecorder) Transfer(eeprom.TransferTrace) {}
+func (r *commandRecorder) Status(eeprom.StatusTrace)     {}
+
+func TestRetryResume(t *testing.T) {
+	s := eeprom.NewSimulator()
+	r := new(commandRecorder)
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C256"))
+	d.SetRetryPolicy(&eeprom.RetryPolicy{Attempts: 2, ChunkSize: 4096})
+	d.SetTracer(r)
+	s.SetFaults(eeprom.Faults{StopAfter: 1000})
+
+	data := make([]byte, 8192)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := d.WritePages(0, data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.Memory()[:len(data)], data) {
+		t.Fatal("unexpected memory contents")
+	}
+	// The failed chunk should resume from the last page confirmed by the
+	// device.
+	want := []uint16{0, 960, 4096}
+	if len(r.starts) != len(want) {
+		t.Fatalf("expected commands at %#x; got %#x", want, r.starts)
+	}
+	for i := range want {
+		if r.starts[i] != want[i] {
+			t.Fatalf("expected commands at %#x; got %#x", want, r.starts)
+		}
+	}
+}
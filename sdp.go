@@ -0,0 +1,163 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+)
+
+// sdpCycle is a single write cycle of a Software Data Protection command
+// sequence. The address is an index into Chip.SDPAddr.
+type sdpCycle struct {
+	addr int
+	data byte
+}
+
+// JEDEC Software Data Protection command sequences.
+var (
+	sdpLock = []sdpCycle{
+		{0, 0xaa}, {1, 0x55}, {0, 0xa0},
+	}
+	sdpUnlock = []sdpCycle{
+		{0, 0xaa}, {1, 0x55}, {0, 0x80},
+		{0, 0xaa}, {1, 0x55}, {0, 0x20},
+	}
+)
+
+// EnableSDP enables Software Data Protection by issuing the JEDEC lock
+// sequence to the bound chip. Once enabled, the chip ignores writes until
+// DisableSDP is called. ErrNoSDP is returned if no chip is bound or the chip
+// does not support Software Data Protection.
+//
+// The chip is then probed by writing the complement of the byte at the first
+// command address and reading it back; ErrSDPFailed is returned if the write
+// was stored, in which case the original byte is restored.
+func (d *Device) EnableSDP() error {
+	return d.EnableSDPContext(context.Background())
+}
+
+// EnableSDPContext is like EnableSDP, but stops between transfers once the
+// given context is done.
+func (d *Device) EnableSDPContext(ctx context.Context) error {
+	return d.sdp(ctx, sdpLock, true)
+}
+
+// DisableSDP disables Software Data Protection by issuing the JEDEC unlock
+// sequence to the bound chip. ErrNoSDP is returned if no chip is bound or the
+// chip does not support Software Data Protection.
+//
+// The chip is then probed as described by EnableSDP; ErrSDPFailed is returned
+// if the write was ignored.
+func (d *Device) DisableSDP() error {
+	return d.DisableSDPContext(context.Background())
+}
+
+// DisableSDPContext is like DisableSDP, but stops between transfers once the
+// given context is done.
+func (d *Device) DisableSDPContext(ctx context.Context) error {
+	return d.sdp(ctx, sdpUnlock, false)
+}
+
+// sdp issues each cycle of the given sequence as a single byte write, and
+// confirms that the chip is protected or unprotected as requested. The
+// writes are sent to the programmer in a single transfer, which allows it to
+// issue consecutive cycles within the byte load cycle time of the chip.
+func (d *Device) sdp(ctx context.Context, seq []sdpCycle, protect bool) error {
+	if d.chip == nil || !d.chip.SDP {
+		return ErrNoSDP
+	}
+	if progress := d.progress; progress != nil {
+		d.progress = nil
+		defer func() { d.progress = progress }()
+	}
+
+	var b bytes.Buffer
+	for _, c := range seq {
+		b.WriteByte('W')
+		binary.Write(&b, binary.LittleEndian, d.chip.SDPAddr[c.addr])
+		binary.Write(&b, binary.LittleEndian, uint16(0))
+		b.WriteByte(c.data)
+	}
+	err := d.withRetry(ctx, func() error {
+		if err := d.selectBank(ctx); err != nil {
+			return err
+		}
+		cmds := b.Bytes()
+		for i := 0; i < len(cmds); i += 6 {
+			d.traceCommand(cmds[i : i+5])
+		}
+		if _, err := d.transfer(ctx, endpointOUT, cmds, d.timeouts.transfer()); err != nil {
+			return err
+		}
+
+		// Each write is acknowledged by its own status word.
+		status := make([]byte, 2*len(seq))
+		if _, err := d.transfer(ctx, endpointIN, status, d.statusTimeout()); err != nil {
+			return err
+		}
+		for i, c := range seq {
+			expected := d.chip.SDPAddr[c.addr] + 1
+			if err := d.check(expected, binary.LittleEndian.Uint16(status[2*i:])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return d.probeSDP(ctx, protect)
+}
+
+// probeSDP writes the complement of the byte at the first command address of
+// the bound chip and reads it back. The original byte is restored if the
+// write was stored. ErrSDPFailed is returned if the write was stored while
+// protected, or ignored while unprotected.
+func (d *Device) probeSDP(ctx context.Context, protect bool) error {
+	addr := d.chip.SDPAddr[0]
+	orig := make([]byte, 1)
+	if err := d.ReadContext(ctx, addr, orig); err != nil {
+		return err
+	}
+	if err := d.WriteBytesContext(ctx, addr, []byte{^orig[0]}); err != nil {
+		return err
+	}
+	data := make([]byte, 1)
+	if err := d.ReadContext(ctx, addr, data); err != nil {
+		return err
+	}
+	if stored := data[0] != orig[0]; stored {
+		if err := d.WriteBytesContext(ctx, addr, orig); err != nil {
+			return err
+		}
+		if protect {
+			return ErrSDPFailed
+		}
+	} else if !protect {
+		return ErrSDPFailed
+	}
+	return nil
+}
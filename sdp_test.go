@@ -0,0 +1,145 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestSDP(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C256"))
+
+	if err := d.EnableSDP(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Protected() {
+		t.Fatal("expected SDP to be enabled")
+	}
+	if err := d.WriteBytes(0x5555, []byte{0x12}); err != nil {
+		t.Fatal(err)
+	}
+	if b := s.Memory()[0x5555]; b != 0xff {
+		t.Fatalf("expected write to be ignored; got %#x", b)
+	}
+
+	if err := d.DisableSDP(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Protected() {
+		t.Fatal("expected SDP to be disabled")
+	}
+	if err := d.WriteBytes(0x5555, []byte{0x12}); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1)
+	if err := d.Read(0x5555, data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0x12 {
+		t.Fatalf("expected write to be stored; got %#x", data[0])
+	}
+}
+
+func TestSDPFailed(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	d.SetChip(eeprom.LookupChip("AT28C256"))
+
+	// Short transfers split the command sequence, which is then stored as
+	// ordinary data.
+	s.SetFaults(eeprom.Faults{MaxTransfer: 1})
+	if err := d.EnableSDP(); err != eeprom.ErrSDPFailed {
+		t.Fatalf("expected ErrSDPFailed; got %v", err)
+	}
+	if s.Protected() {
+		t.Fatal("expected SDP to be disabled")
+	}
+	if b := s.Memory()[0x5555]; b != 0xa0 {
+		t.Fatalf("expected last cycle to be restored; got %#x", b)
+	}
+
+	s.SetFaults(eeprom.Faults{})
+	if err := d.EnableSDP(); err != nil {
+		t.Fatal(err)
+	}
+	s.SetFaults(eeprom.Faults{MaxTransfer: 1})
+	if err := d.DisableSDP(); err != eeprom.ErrSDPFailed {
+		t.Fatalf("expected ErrSDPFailed; got %v", err)
+	}
+	if !s.Protected() {
+		t.Fatal("expected SDP to remain enabled")
+	}
+}
+
+func TestSDPUnsupported(t *testing.T) {
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	if err := d.EnableSDP(); err != eeprom.ErrNoSDP {
+		t.Fatalf("expected ErrNoSDP; got %v", err)
+	}
+	d.SetChip(eeprom.LookupChip("AT28C16"))
+	if err := d.DisableSDP(); err != eeprom.ErrNoSDP {
+		t.Fatalf("expected ErrNoSDP; got %v", err)
+	}
+}
+
+func TestSimulatorSingleByteWrites(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	// A partial command sequence is stored as ordinary data.
+	if err := d.WriteBytes(0x1555, []byte{0xaa}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteBytes(0x0000, []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1)
+	if err := d.Read(0x1555, data); err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 0xaa || s.Memory()[0] != 0x01 {
+		t.Fatalf("unexpected memory %#x %#x", data[0], s.Memory()[0])
+	}
+
+	// A command sequence is not recognized unless its cycles are sent in a
+	// single transfer.
+	for _, w := range []struct {
+		addr uint16
+		data byte
+	}{{0x5555, 0xaa}, {0x2aaa, 0x55}, {0x5555, 0xa0}} {
+		if err := d.WriteBytes(w.addr, []byte{w.data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Protected() {
+		t.Fatal("expected SDP to be disabled")
+	}
+	if s.Memory()[0x5555] != 0xa0 || s.Memory()[0x2aaa] != 0x55 {
+		t.Fatal("expected command cycles to be stored")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import "sync"
+
+// DebugLevel is the verbosity of messages logged by the USB backend.
+type DebugLevel int
+
+// Debug levels accepted by SetDebug; these match enum libusb_log_level.
+const (
+	DebugNone DebugLevel = iota
+	DebugError
+	DebugWarning
+	DebugInfo
+	DebugDebug
+)
+
+// Session is a session with the USB subsystem of the host, through which
+// supported devices are discovered. Sessions are independent of one another,
+// and must be closed once they are no longer needed.
+//
+// The package-level functions Walk, First, List, Devices, Watch and SetMatches
+// use a default session, which is created when first needed and is never closed.
+type Session struct {
+	usbSession
+	bus bus // devices attached to the host
+
+	matchesMu sync.RWMutex
+	matches   []Match // recognized devices; DefaultMatch if empty
+}
+
+// bus enumerates the devices attached to the host. It is implemented by the
+// backend's usbSession, and may be substituted by tests.
+type bus interface {
+	// devices calls fn with the vendor and product IDs of each device,
+	// along with a function that returns a transport for the device
+	// given the match that recognized it. The transport function may
+	// only be called before fn returns.
+	devices(fn func(vendor, product uint16, transport func(Match) Transport) error) error
+}
+
+// NewSession returns a new session.
+func NewSession() (*Session, error) {
+	s := new(Session)
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	s.bus = &s.usbSession
+	return s, nil
+}
+
+var (
+	defaultMu      sync.Mutex
+	defaultSession *Session
+)
+
+// DefaultSession returns the session used by the package-level functions,
+// creating it if necessary. If the session cannot be created, the error is
+// returned and creation is attempted again by the next call. The default
+// session must not be closed.
+func DefaultSession() (*Session, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultSession == nil {
+		s, err := NewSession()
+		if err != nil {
+			return nil, err
+		}
+		defaultSession = s
+	}
+	return defaultSession, nil
+}
+
+// Walk calls the specified function for each supported device attached to the
+// host. To ensure proper reference counting, Open must be called within the
+// context of a Walk; use List to open devices after enumeration.
+func (s *Session) Walk(fn func(*Device) error) error {
+	var found int
+
+	err := s.bus.devices(func(vendor, product uint16, transport func(Match) Transport) error {
+		m, ok := s.lookupMatch(vendor, product)
+		if !ok {
+			return nil
+		}
+		found++
+		return fn(&Device{t: transport(m)})
+	})
+	if err == nil && found == 0 {
+		return ErrNoDevices
+	}
+	return err
+}
+
+// Walk calls Walk on the default session.
+func Walk(fn func(*Device) error) error {
+	s, err := DefaultSession()
+	if err != nil {
+		return err
+	}
+	return s.Walk(fn)
+}
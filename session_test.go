@@ -0,0 +1,105 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import "testing"
+
+func TestSession(t *testing.T) {
+	s, err := NewSession()
+	if err != nil {
+		t.Skip(err)
+	}
+	s.SetDebug(DebugWarning)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultSession(t *testing.T) {
+	s1, err := DefaultSession()
+	if err != nil {
+		t.Skip(err)
+	}
+	s2, err := DefaultSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1 != s2 {
+		t.Fatal("expected the same session")
+	}
+}
+
+// fakeBus is a bus with a Simulator attached for each of the given IDs.
+type fakeBus []Match
+
+func (b fakeBus) devices(fn func(vendor, product uint16, transport func(Match) Transport) error) error {
+	for _, id := range b {
+		if err := fn(id.Vendor, id.Product, func(Match) Transport { return NewSimulator() }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSessionIndependent(t *testing.T) {
+	other := Match{Vendor: 0x1209, Product: 0x0001}
+	bus := fakeBus{DefaultMatch, other, other}
+
+	s1 := &Session{bus: bus}
+	s2 := &Session{bus: bus}
+	s2.SetMatches(other)
+
+	walk := func(s *Session) int {
+		var n int
+		err := s.Walk(func(d *Device) error {
+			n++
+			return nil
+		})
+		if err != nil && err != ErrNoDevices {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := walk(s1); n != 1 {
+		t.Fatalf("expected 1 device; got %d", n)
+	}
+	if n := walk(s2); n != 2 {
+		t.Fatalf("expected 2 devices; got %d", n)
+	}
+
+	// Setting the matches of one session does not affect another, or the
+	// default session.
+	s1.SetMatches(Match{Vendor: 0xffff, Product: 0xffff})
+	if n := walk(s1); n != 0 {
+		t.Fatalf("expected no devices; got %d", n)
+	}
+	if devices, err := s2.List(); err != nil || len(devices) != 2 {
+		t.Fatalf("expected 2 devices; got %v, %v", devices, err)
+	}
+	if s, err := DefaultSession(); err == nil {
+		if _, ok := s.lookupMatch(other.Vendor, other.Product); ok {
+			t.Fatal("unexpected match in default session")
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const simPacketSize = 64
+
+// Faults describes misbehavior injected by a Simulator. The zero value
+// injects no faults.
+type Faults struct {
+	// MaxTransfer, if non-zero, limits the number of bytes moved by each
+	// transfer, producing short transfers.
+	MaxTransfer int
+
+	// Err, if non-nil, is returned by the transfer that would exceed
+	// ErrAfter bytes. Subsequent transfers fail with the same error until
+	// the Simulator is reset, after which the fault is not repeated. Use
+	// ErrTimeout to simulate a transfer timeout.
+	Err      error
+	ErrAfter int
+
+	// StatusMask is XORed with each status word returned, producing
+	// status mismatches.
+	StatusMask uint16
+
+	// StuckHigh and StuckLow are bit masks of data bits that are stuck at
+	// one and zero, respectively, when stored in memory.
+	StuckHigh, StuckLow byte
+
+	// DropPage, if non-zero, causes every DropPage-th page of a page write
+	// to be acknowledged but not stored.
+	DropPage int
+
+	// StopAfter, if non-zero, causes the write command that would store
+	// byte StopAfter to stop at that byte: the remaining data is
+	// discarded, and the status word reports the address reached. The
+	// fault is not repeated.
+	StopAfter int
+}
+
+// Simulator is a Transport that emulates a programmer in memory. Commands
+// are decoded exactly as an attached programmer would decode them, which
+// allows a Device to be exercised without hardware:
+//
+//	d := eeprom.NewDevice(eeprom.NewSimulator())
+//
+// The simulated memory array initially contains erased (0xff) data. Software
+// Data Protection is emulated for single byte writes using the command
+// addresses of an 8 KiB part, which are aliased throughout larger parts. As
+// the byte load cycle time of a chip is far shorter than a USB round trip,
+// the cycles of a command sequence must be sent in a single transfer.
+type Simulator struct {
+	mem      []byte
+	extended bool  // supports Bank Select
+	bank     uint8 // selected bank
+
+	cmd  []byte       // partial command packet
+	op   byte         // command awaiting data
+	addr uint16       // next address to write
+	n    int          // number of bytes remaining
+	page int          // number of pages written
+	in   bytes.Buffer // pending response
+
+	single    bool       // write command stores a single byte
+	seq       []simWrite // pending SDP command cycles
+	protected bool       // SDP is enabled
+
+	faults  Faults
+	count   int  // number of bytes transferred
+	failed  bool // transfers are failing
+	tripped bool // Err has been returned
+	stored  int  // number of bytes stored by write commands
+	halted  bool // write command is discarding data
+	stopped bool // StopAfter has been reached
+}
+
+// simWrite is a single byte write that may be part of an SDP command sequence.
+type simWrite struct {
+	addr uint16
+	data byte
+}
+
+// NewSimulator returns a new Simulator with MaxBytes of memory.
+func NewSimulator() *Simulator {
+	s := &Simulator{mem: make([]byte, MaxBytes)}
+	s.erase()
+	return s
+}
+
+// NewExtendedSimulator returns a new Simulator with size bytes of memory that
+// supports extended addressing. Addresses beyond size wrap around, as they do
+// on a chip with fewer address lines than the programmer.
+func NewExtendedSimulator(size int) *Simulator {
+	s := &Simulator{mem: make([]byte, size), extended: true}
+	s.erase()
+	return s
+}
+
+// Memory returns the simulated memory array. The returned slice aliases the
+// Simulator and may be used to inspect or preload its contents.
+func (s *Simulator) Memory() []byte { return s.mem }
+
+// SetFaults sets the faults injected by the Simulator and restarts the count
+// of bytes transferred.
+func (s *Simulator) SetFaults(f Faults) {
+	s.faults = f
+	s.count = 0
+	s.failed = false
+	s.tripped = false
+	s.stored = 0
+	s.halted = false
+	s.stopped = false
+}
+
+// BulkOut decodes command packets and stores data for pending writes.
+func (s *Simulator) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	n, err := s.limit(len(data))
+	if s.n > 0 && s.op == 'P' {
+		s.page++
+	}
+	for i, b := range data[:n] {
+		if s.n > 0 {
+			s.store(b)
+			continue
+		}
+		s.cmd = append(s.cmd, b)
+		if err := s.decode(); err != nil {
+			return i, err
+		}
+	}
+	// The byte load cycle time expires before the next transfer.
+	s.flush()
+	return n, err
+}
+
+// BulkIn returns data for pending reads followed by the status word.
+func (s *Simulator) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	n, err := s.limit(len(data))
+	if err == nil && s.in.Len() == 0 {
+		return 0, ErrTimeout
+	}
+	n, _ = s.in.Read(data[:n])
+	return n, err
+}
+
+// MaxPacketSize returns the maximum packet size of a full-speed bulk
+// endpoint.
+func (s *Simulator) MaxPacketSize() int { return simPacketSize }
+
+// Reset discards any partial command and pending response, and selects the
+// first bank. The contents of the simulated memory array are preserved.
+func (s *Simulator) Reset() error {
+	s.bank = 0
+	s.cmd = s.cmd[:0]
+	s.n = 0
+	s.in.Reset()
+	s.failed = false
+	s.halted = false
+	return nil
+}
+
+// Protected reports whether Software Data Protection is enabled.
+func (s *Simulator) Protected() bool { return s.protected }
+
+// Close is a no-op.
+func (s *Simulator) Close() error { return nil }
+
+func (s *Simulator) limit(n int) (int, error) {
+	if s.failed {
+		return 0, s.faults.Err
+	}
+	if n > simPacketSize {
+		n = simPacketSize
+	}
+	if m := s.faults.MaxTransfer; m > 0 && n > m {
+		n = m
+	}
+	if s.faults.Err != nil && !s.tripped && s.count+n > s.faults.ErrAfter {
+		n = s.faults.ErrAfter - s.count
+		s.count += n
+		s.failed = true
+		s.tripped = true
+		return n, s.faults.Err
+	}
+	s.count += n
+	return n, nil
+}
+
+func (s *Simulator) decode() error {
+	switch s.cmd[0] {
+	case 'Z':
+		s.flush()
+		s.cmd = s.cmd[:0]
+		s.erase()
+		s.status(0)
+	case 'B':
+		if !s.extended {
+			s.cmd = s.cmd[:0]
+			return fmt.Errorf("simulator: invalid command %#x", 'B')
+		}
+		if len(s.cmd) < 2 {
+			return nil
+		}
+		s.flush()
+		s.bank = s.cmd[1]
+		s.cmd = s.cmd[:0]
+		s.status(uint16(s.bank))
+		return nil
+	case 'R', 'W', 'P':
+		if len(s.cmd) < 5 {
+			return nil
+		}
+		start := binary.LittleEndian.Uint16(s.cmd[1:])
+		n := int(binary.LittleEndian.Uint16(s.cmd[3:])) + 1
+		s.op = s.cmd[0]
+		s.cmd = s.cmd[:0]
+		if s.single = s.op == 'W' && n == 1; !s.single {
+			s.flush()
+		}
+
+		if s.op == 'R' {
+			for i := 0; i < n; i++ {
+				s.in.WriteByte(s.mem[s.index(start)])
+				start++
+			}
+			s.status(start)
+		} else {
+			s.addr, s.n, s.page = start, n, 0
+		}
+	default:
+		op := s.cmd[0]
+		s.cmd = s.cmd[:0]
+		return fmt.Errorf("simulator: invalid command %#x", op)
+	}
+	return nil
+}
+
+func (s *Simulator) store(b byte) {
+	if s.faults.StopAfter > 0 && !s.stopped && s.stored == s.faults.StopAfter {
+		s.halted = true
+		s.stopped = true
+	}
+	if !s.halted {
+		if !s.single || !s.cycle(s.addr, b) {
+			if s.op != 'P' || s.faults.DropPage == 0 || s.page%s.faults.DropPage != 0 {
+				s.write(s.addr, b)
+			}
+		}
+		s.addr++
+		s.stored++
+	}
+	if s.n--; s.n == 0 {
+		s.halted = false
+		s.status(s.addr)
+	}
+}
+
+func (s *Simulator) write(addr uint16, b byte) {
+	if !s.protected {
+		s.mem[s.index(addr)] = b&^s.faults.StuckLow | s.faults.StuckHigh
+	}
+}
+
+// index returns the index of addr within the selected bank.
+func (s *Simulator) index(addr uint16) int {
+	return (int(s.bank)<<16 | int(addr)) % len(s.mem)
+}
+
+// cycle records a single byte write as a possible SDP command cycle. It
+// returns false if the write is not part of a command sequence, in which case
+// any pending cycles are written as ordinary data.
+func (s *Simulator) cycle(addr uint16, b byte) bool {
+	s.seq = append(s.seq, simWrite{addr, b})
+	switch {
+	case s.match(sdpUnlock):
+		if len(s.seq) == len(sdpUnlock) {
+			s.protected = false
+			s.seq = s.seq[:0]
+		}
+		return true
+	case s.match(sdpLock):
+		if len(s.seq) == len(sdpLock) {
+			s.protected = true
+			s.seq = s.seq[:0]
+		}
+		return true
+	}
+	s.seq = s.seq[:len(s.seq)-1]
+	s.flush()
+	return false
+}
+
+// match reports whether the pending cycles are a prefix of seq.
+func (s *Simulator) match(seq []sdpCycle) bool {
+	addrs := [2]uint16{0x1555, 0x0aaa}
+	if len(s.seq) > len(seq) {
+		return false
+	}
+	for i, w := range s.seq {
+		if w.addr&0x1fff != addrs[seq[i].addr] || w.data != seq[i].data {
+			return false
+		}
+	}
+	return true
+}
+
+// flush writes pending cycles that did not form a command sequence.
+func (s *Simulator) flush() {
+	for _, w := range s.seq {
+		s.write(w.addr, w.data)
+	}
+	s.seq = s.seq[:0]
+}
+
+func (s *Simulator) erase() {
+	for i := range s.mem {
+		s.mem[i] = 0xff&^s.faults.StuckLow | s.faults.StuckHigh
+	}
+}
+
+func (s *Simulator) status(addr uint16) {
+	binary.Write(&s.in, binary.LittleEndian, addr^s.faults.StatusMask)
+}
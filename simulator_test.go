@@ -0,0 +1,148 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+func TestSimulatorErase(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	mem := s.Memory()
+	for i := range mem {
+		mem[i] = 0
+	}
+	if err := d.Erase(); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range mem {
+		if b != 0xff {
+			t.Fatalf("mem[%d]: expected 0xff; got %#x", i, b)
+		}
+	}
+}
+
+func TestSimulatorReset(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+
+	if _, err := s.BulkOut([]byte{'W', 0x00, 0x00, 0x0f, 0x00, 0x00}, 0); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset()
+
+	data := []byte{0x01, 0x02}
+	if err := d.WriteBytes(0x10, data); err != nil {
+		t.Fatal(err)
+	}
+	if mem := s.Memory(); mem[0x10] != 0x01 || mem[0x11] != 0x02 {
+		t.Fatalf("expected % x; got % x", data, mem[0x10:0x12])
+	}
+}
+
+func TestSimulatorInvalidCommand(t *testing.T) {
+	s := eeprom.NewSimulator()
+
+	if _, err := s.BulkOut([]byte{'X'}, 0); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSimulatorFaults(t *testing.T) {
+	errFault := errors.New("fault")
+	tests := []struct {
+		name   string
+		faults eeprom.Faults
+		err    error
+	}{
+		{"MaxTransfer", eeprom.Faults{MaxTransfer: 3}, nil},
+		{"Timeout", eeprom.Faults{Err: eeprom.ErrTimeout}, eeprom.ErrTimeout},
+		{"ErrAfter", eeprom.Faults{Err: errFault, ErrAfter: 100}, errFault},
+	}
+	for _, test := range tests {
+		s := eeprom.NewSimulator()
+		d := eeprom.NewDevice(s)
+		s.SetFaults(test.faults)
+
+		data := make([]byte, 256)
+		if err := d.WriteBytes(0, data); err != test.err {
+			t.Fatalf("%s: expected %v; got %v", test.name, test.err, err)
+		}
+		if test.err == nil {
+			continue
+		}
+		if err := d.WriteBytes(0, data); err != test.err {
+			t.Fatalf("%s: expected %v before reset; got %v", test.name, test.err, err)
+		}
+		d.Reset()
+		if err := d.WriteBytes(0, data); err != nil {
+			t.Fatalf("%s: expected recovery after reset; got %v", test.name, err)
+		}
+	}
+}
+
+func TestSimulatorStatusMask(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	s.SetFaults(eeprom.Faults{StatusMask: 0x8000})
+
+	if err := d.Erase(); err == nil {
+		t.Fatal("expected status mismatch")
+	}
+}
+
+func TestSimulatorStuckBits(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	s.SetFaults(eeprom.Faults{StuckHigh: 0x01, StuckLow: 0x80})
+
+	if err := d.WriteBytes(0, []byte{0x80, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if mem := s.Memory(); mem[0] != 0x01 || mem[1] != 0x01 {
+		t.Fatalf("expected 01 01; got % x", mem[:2])
+	}
+}
+
+func TestSimulatorDropPage(t *testing.T) {
+	s := eeprom.NewSimulator()
+	d := eeprom.NewDevice(s)
+	s.SetFaults(eeprom.Faults{DropPage: 2})
+	d.SetPageSize(4)
+
+	data := make([]byte, 16)
+	if err := d.WritePages(0, data); err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range s.Memory()[:len(data)] {
+		if dropped := i/4%2 == 1; dropped != (b == 0xff) {
+			t.Fatalf("mem[%d]: unexpected %#x", i, b)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import "time"
+
+const (
+	defaultTransferTimeout = 2500 * time.Millisecond
+	defaultStatusMargin    = 100 * time.Millisecond
+	defaultSettleTime      = 500 * time.Millisecond
+)
+
+// Timeouts describes how long a Device waits for the programmer. Zero values
+// select the defaults.
+type Timeouts struct {
+	// Transfer is the time allowed for each packet of a command or of
+	// its data. The default is 2.5s.
+	Transfer time.Duration
+
+	// PerByte, if non-zero, is added to the time allowed for each packet
+	// for every byte in the packet. This accommodates chips whose write
+	// cycle delays page writes beyond the transfer timeout.
+	PerByte time.Duration
+
+	// Status is the time allowed for the status word reported once a
+	// command is complete. The default is the transfer timeout. If a chip
+	// is bound and its write cycle time allows for longer, the programmer
+	// may still be writing the final packet of data, so the default is
+	// instead a write cycle for each byte of a packet, plus 100ms.
+	Status time.Duration
+
+	// Settle is the time Reset waits for the device to settle. The
+	// default is 500ms; a negative value disables the wait.
+	Settle time.Duration
+}
+
+// SetTimeouts sets how long the device waits for the programmer.
+func (d *Device) SetTimeouts(t Timeouts) { d.timeouts = t }
+
+// Timeouts returns how long the device waits for the programmer.
+func (d *Device) Timeouts() Timeouts { return d.timeouts }
+
+func (t Timeouts) transfer() time.Duration {
+	if t.Transfer == 0 {
+		return defaultTransferTimeout
+	}
+	return t.Transfer
+}
+
+// statusTimeout returns the time allowed for a status word.
+func (d *Device) statusTimeout() time.Duration {
+	if d.timeouts.Status != 0 {
+		return d.timeouts.Status
+	}
+	t := d.timeouts.transfer()
+	if d.chip != nil && d.chip.WriteCycle > 0 {
+		if c := defaultStatusMargin + time.Duration(d.t.MaxPacketSize())*d.chip.WriteCycle; c > t {
+			t = c
+		}
+	}
+	return t
+}
+
+// scale returns the timeout of a packet of n bytes.
+func (t Timeouts) scale(base time.Duration, n int) time.Duration {
+	return base + time.Duration(n)*t.PerByte
+}
+
+// settle waits for the device to settle after a reset.
+func (d *Device) settle() {
+	switch t := d.timeouts.Settle; {
+	case t == 0:
+		time.Sleep(defaultSettleTime)
+	case t > 0:
+		time.Sleep(t)
+	}
+}
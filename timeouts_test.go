@@ -0,0 +1,97 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+// timeoutTransport records the timeout of each transfer.
+type timeoutTransport struct {
+	*eeprom.Simulator
+	timeouts []time.Duration
+}
+
+func (t *timeoutTransport) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	t.timeouts = append(t.timeouts, timeout)
+	return t.Simulator.BulkOut(data, timeout)
+}
+
+func (t *timeoutTransport) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	t.timeouts = append(t.timeouts, timeout)
+	return t.Simulator.BulkIn(data, timeout)
+}
+
+func TestTimeouts(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name     string
+		timeouts eeprom.Timeouts
+		chip     string
+		want     []time.Duration
+	}{
+		{"Default", eeprom.Timeouts{}, "",
+			[]time.Duration{2500 * ms, 2500 * ms, 2500 * ms, 2500 * ms}},
+		{"Chip", eeprom.Timeouts{}, "AT28C256",
+			[]time.Duration{2500 * ms, 2500 * ms, 2500 * ms, 2500 * ms}},
+		{"ChipWriteCycle", eeprom.Timeouts{Transfer: 500 * ms}, "AT28C256",
+			[]time.Duration{500 * ms, 500 * ms, 500 * ms, 740 * ms}},
+		{"ChipStatus", eeprom.Timeouts{Status: time.Second}, "AT28C256",
+			[]time.Duration{2500 * ms, 2500 * ms, 2500 * ms, time.Second}},
+		{"Transfer", eeprom.Timeouts{Transfer: time.Second}, "",
+			[]time.Duration{time.Second, time.Second, time.Second, time.Second}},
+		{"Scaled", eeprom.Timeouts{Transfer: time.Second, PerByte: ms, Status: 3 * time.Second}, "",
+			[]time.Duration{1005 * ms, 1064 * ms, 1036 * ms, 3002 * ms}},
+	}
+	for _, test := range tests {
+		tt := &timeoutTransport{Simulator: eeprom.NewSimulator()}
+		d := eeprom.NewDevice(tt)
+		d.SetTimeouts(test.timeouts)
+		if test.chip != "" {
+			d.SetChip(eeprom.LookupChip(test.chip))
+		}
+
+		if err := d.WriteBytes(0, make([]byte, 100)); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(tt.timeouts, test.want) {
+			t.Errorf("%s: expected %v; got %v", test.name, test.want, tt.timeouts)
+		}
+	}
+}
+
+func TestSettle(t *testing.T) {
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	d.SetTimeouts(eeprom.Timeouts{Settle: -1})
+
+	start := time.Now()
+	d.Reset()
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Fatalf("expected reset without settling; took %v", elapsed)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Tracer receives events describing the traffic between a Device and a
+// programmer. Methods are called synchronously by the goroutine performing
+// the operation, and must not retain slices passed to them.
+type Tracer interface {
+	// Command is called before each command packet is sent.
+	Command(CommandTrace)
+
+	// Transfer is called after each bulk transfer completes.
+	Transfer(TransferTrace)
+
+	// Status is called after each status word is read.
+	Status(StatusTrace)
+}
+
+// CommandTrace describes a command packet sent to the device.
+type CommandTrace struct {
+	Op     byte   // command: 'R', 'W', 'P', 'Z' or 'B'
+	Start  uint16 // starting address, or the bank for Bank Select
+	N      int    // number of data bytes; zero for Chip Erase and Bank Select
+	Packet []byte // command packet as sent to the device
+}
+
+func (c CommandTrace) String() string {
+	switch c.Op {
+	case 'Z':
+		return fmt.Sprintf("command %c [% x]", c.Op, c.Packet)
+	case 'B':
+		return fmt.Sprintf("command %c bank %d [% x]", c.Op, c.Start, c.Packet)
+	}
+	return fmt.Sprintf("command %c %#04x+%d [% x]", c.Op, c.Start, c.N, c.Packet)
+}
+
+// TransferTrace describes a single bulk transfer.
+type TransferTrace struct {
+	Endpoint    uint8  // endpoint address; bit 7 is set for IN endpoints
+	Length      int    // number of bytes requested
+	Transferred int    // number of bytes transferred
+	Data        []byte // data sent, or data received
+	Start       time.Time
+	Duration    time.Duration
+	Err         error // error returned by the transport, if any
+}
+
+func (t TransferTrace) String() string {
+	dir := "OUT"
+	if t.Endpoint&0x80 != 0 {
+		dir = "IN"
+	}
+	s := fmt.Sprintf("transfer %s %#02x %d/%d bytes in %v", dir, t.Endpoint, t.Transferred, t.Length, t.Duration)
+	if t.Err != nil {
+		s += ": " + t.Err.Error()
+	}
+	return s
+}
+
+// StatusTrace describes a status word read from the device.
+type StatusTrace struct {
+	Expected uint16
+	Got      uint16
+}
+
+func (s StatusTrace) String() string {
+	if s.Got != s.Expected {
+		return fmt.Sprintf("status %#04x; expected %#04x", s.Got, s.Expected)
+	}
+	return fmt.Sprintf("status %#04x", s.Got)
+}
+
+// MultiTracer returns a Tracer that passes each event to every given tracer
+// in turn.
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(append([]Tracer(nil), tracers...))
+}
+
+type multiTracer []Tracer
+
+func (m multiTracer) Command(c CommandTrace) {
+	for _, t := range m {
+		t.Command(c)
+	}
+}
+
+func (m multiTracer) Transfer(x TransferTrace) {
+	for _, t := range m {
+		t.Transfer(x)
+	}
+}
+
+func (m multiTracer) Status(s StatusTrace) {
+	for _, t := range m {
+		t.Status(s)
+	}
+}
+
+var errCancelled = errors.New("transfer cancelled")
+
+// SetTracer sets the tracer that receives events describing the traffic
+// between the device and the programmer. A nil tracer, which is the default,
+// disables tracing.
+func (d *Device) SetTracer(t Tracer) { d.tracer = t }
+
+// traceCommand reports the given command packet to the tracer.
+func (d *Device) traceCommand(cmd []byte) {
+	if d.tracer == nil {
+		return
+	}
+	c := CommandTrace{Op: cmd[0], Packet: cmd}
+	switch {
+	case c.Op == 'B' && len(cmd) > 1:
+		c.Start = uint16(cmd[1])
+	case len(cmd) >= 5:
+		c.Start = uint16(cmd[1]) | uint16(cmd[2])<<8
+		c.N = int(uint16(cmd[3])|uint16(cmd[4])<<8) + 1
+	}
+	d.tracer.Command(c)
+}
+
+// traceTransfer reports a bulk transfer of data started at the given time to
+// the tracer.
+func (d *Device) traceTransfer(endpoint uint8, data []byte, transferred int, start time.Time, err error) {
+	if d.tracer == nil {
+		return
+	}
+	t := TransferTrace{
+		Endpoint:    d.address(endpoint),
+		Length:      len(data),
+		Transferred: transferred,
+		Data:        data,
+		Start:       start,
+		Duration:    time.Since(start),
+		Err:         err,
+	}
+	if endpoint == endpointIN {
+		t.Data = data[:transferred]
+	}
+	d.tracer.Transfer(t)
+}
+
+// address returns the address of the given endpoint of the underlying
+// device.
+func (d *Device) address(endpoint uint8) uint8 {
+	if t, ok := d.t.(matcher); ok {
+		if endpoint == endpointIN {
+			return t.matched().in()
+		}
+		return t.matched().out()
+	}
+	return endpoint
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sstallion/go-eeprom"
+)
+
+// traceRecorder records the events it receives as strings, omitting
+// durations.
+type traceRecorder struct {
+	events []string
+}
+
+func (r *traceRecorder) Command(c eeprom.CommandTrace) {
+	r.events = append(r.events, c.String())
+}
+
+func (r *traceRecorder) Transfer(t eeprom.TransferTrace) {
+	t.Duration = 0
+	r.events = append(r.events, t.String())
+}
+
+func (r *traceRecorder) Status(s eeprom.StatusTrace) {
+	r.events = append(r.events, s.String())
+}
+
+func TestTracer(t *testing.T) {
+	r := new(traceRecorder)
+	d := eeprom.NewDevice(eeprom.NewSimulator())
+	d.SetTracer(r)
+
+	if err := d.WriteBytes(0x1234, []byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Erase(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"command W 0x1234+4 [57 34 12 03 00]",
+		"transfer OUT 0x01 5/5 bytes in 0s",
+		"transfer OUT 0x01 4/4 bytes in 0s",
+		"transfer IN 0x81 2/2 bytes in 0s",
+		"status 0x1238",
+		"command Z [5a]",
+		"transfer OUT 0x01 1/1 bytes in 0s",
+		"transfer IN 0x81 2/2 bytes in 0s",
+		"status 0x0000",
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("expected %q; got %q", want, r.events)
+	}
+}
+
+func TestTracerStatus(t *testing.T) {
+	r := new(traceRecorder)
+	s := eeprom.NewSimulator()
+	s.SetFaults(eeprom.Faults{StatusMask: 0x8000})
+	d := eeprom.NewDevice(s)
+	d.SetTracer(r)
+
+	var e *eeprom.StatusError
+	if err := d.Erase(); !errors.As(err, &e) {
+		t.Fatalf("expected *StatusError; got %v", err)
+	}
+	if got, want := r.events[len(r.events)-1], "status 0x8000; expected 0x0000"; got != want {
+		t.Fatalf("expected %q; got %q", want, got)
+	}
+}
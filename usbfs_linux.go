@@ -0,0 +1,434 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+//go:build usbfs || !cgo
+// +build usbfs !cgo
+
+package eeprom
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Paths used to discover and open devices; these are variables so that tests
+// may substitute a fake tree.
+var (
+	sysfsRoot = "/sys/bus/usb/devices"
+	devfsRoot = "/dev/bus/usb"
+)
+
+// usbdevfs_bulktransfer from <linux/usbdevice_fs.h>.
+type usbfsBulkTransfer struct {
+	ep      uint32
+	len     uint32
+	timeout uint32
+	data    unsafe.Pointer
+}
+
+// usbdevfs_urb from <linux/usbdevice_fs.h>, without iso_frame_desc.
+type usbfsURB struct {
+	typ          uint8
+	endpoint     uint8
+	status       int32
+	flags        uint32
+	buffer       unsafe.Pointer
+	bufferLength int32
+	actualLength int32
+	startFrame   int32
+	numPackets   int32
+	errorCount   int32
+	signr        uint32
+	usercontext  unsafe.Pointer
+}
+
+const usbfsURBTypeBulk = 3
+
+const (
+	usbfsBulk             = 0xc0005502 | uintptr(unsafe.Sizeof(usbfsBulkTransfer{}))<<16 // _IOWR('U', 2, ...)
+	usbfsSubmitURB        = 0x8000550a | uintptr(unsafe.Sizeof(usbfsURB{}))<<16          // _IOR('U', 10, ...)
+	usbfsDiscardURB       = 0x0000550b                                                   // _IO('U', 11)
+	usbfsReapURB          = 0x4000550c | uintptr(unsafe.Sizeof(uintptr(0)))<<16          // _IOW('U', 12, void *)
+	usbfsClaimInterface   = 0x8004550f                                                   // _IOR('U', 15, unsigned int)
+	usbfsReleaseInterface = 0x80045510                                                   // _IOR('U', 16, unsigned int)
+	usbfsReset            = 0x00005514                                                   // _IO('U', 20)
+)
+
+// usbfsTransport is a Transport backed by the Linux usbfs interface. It does
+// not require cgo.
+type usbfsTransport struct {
+	match      Match
+	dir        string // sysfs directory
+	bus, addr  int
+	packetSize int
+	err        error // reported by open
+	f          *os.File
+
+	mu     sync.Mutex
+	reaped map[*usbfsURB]bool // URBs reaped while waiting for another
+}
+
+func (t *usbfsTransport) ID() string {
+	return fmt.Sprintf("%d:%d", t.bus, t.addr)
+}
+
+func (t *usbfsTransport) matched() Match { return t.match }
+
+func (t *usbfsTransport) path() string {
+	return filepath.Join(devfsRoot, fmt.Sprintf("%03d/%03d", t.bus, t.addr))
+}
+
+func (t *usbfsTransport) info() (DeviceInfo, error) {
+	info := DeviceInfo{
+		ID:            t.ID(),
+		Bus:           t.bus,
+		Address:       t.addr,
+		PortPath:      filepath.Base(t.dir),
+		OutPacketSize: t.packetSize,
+	}
+	info.Manufacturer, _ = sysfsAttr(t.dir, "manufacturer")
+	info.Product, _ = sysfsAttr(t.dir, "product")
+	info.SerialNumber, _ = sysfsAttr(t.dir, "serial")
+
+	version, err := sysfsInt(t.dir, "bcdDevice", 16)
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	info.Version = uint16(version)
+
+	speed, err := sysfsAttr(t.dir, "speed")
+	if err != nil {
+		return DeviceInfo{}, err
+	}
+	switch speed {
+	case "1.5":
+		info.Speed = SpeedLow
+	case "12":
+		info.Speed = SpeedFull
+	case "480":
+		info.Speed = SpeedHigh
+	case "5000":
+		info.Speed = SpeedSuper
+	case "10000", "20000":
+		info.Speed = SpeedSuperPlus
+	}
+
+	if ep, err := t.endpoint(t.match.in()); err == nil {
+		info.InPacketSize, _ = sysfsInt(ep, "wMaxPacketSize", 16)
+	}
+	return info, nil
+}
+
+// endpoint returns the sysfs directory describing the given endpoint.
+func (t *usbfsTransport) endpoint(endpoint uint8) (string, error) {
+	name := filepath.Base(t.dir)
+	eps, _ := filepath.Glob(filepath.Join(t.dir, fmt.Sprintf("%s:*.%d", name, t.match.Interface),
+		fmt.Sprintf("ep_%02x", endpoint)))
+	if len(eps) == 0 {
+		return "", fmt.Errorf("%s: endpoint not found", name)
+	}
+	return eps[0], nil
+}
+
+func (t *usbfsTransport) open() error {
+	if t.err != nil {
+		return t.err
+	}
+	f, err := os.OpenFile(t.path(), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	iface := uint32(t.match.Interface)
+	if err := ioctl(f, usbfsClaimInterface, unsafe.Pointer(&iface)); err != nil {
+		f.Close()
+		return err
+	}
+	t.f = f
+	return nil
+}
+
+func (t *usbfsTransport) reopen() error {
+	t.Close()
+	return t.open()
+}
+
+func (t *usbfsTransport) BulkOut(data []byte, timeout time.Duration) (int, error) {
+	return t.bulkTransfer(t.match.out(), data, timeout)
+}
+
+func (t *usbfsTransport) BulkIn(data []byte, timeout time.Duration) (int, error) {
+	return t.bulkTransfer(t.match.in(), data, timeout)
+}
+
+func (t *usbfsTransport) bulkTransfer(endpoint uint8, data []byte, timeout time.Duration) (int, error) {
+	bulk := usbfsBulkTransfer{
+		ep:      uint32(endpoint),
+		len:     uint32(len(data)),
+		timeout: uint32(timeout / time.Millisecond),
+		data:    unsafe.Pointer(&data[0]),
+	}
+	r, _, errno := syscall.Syscall(syscall.SYS_IOCTL, t.f.Fd(), usbfsBulk, uintptr(unsafe.Pointer(&bulk)))
+	if errno != 0 {
+		return 0, usbfsError(errno)
+	}
+	return int(r), nil
+}
+
+// usbfsPending is a URB in flight. usbfs does not time out URBs, so the URB
+// is discarded if it does not complete before its timer fires.
+type usbfsPending struct {
+	t        *usbfsTransport
+	urb      usbfsURB
+	data     []byte // referenced by urb.buffer
+	timer    *time.Timer
+	timedOut int32
+}
+
+func (t *usbfsTransport) submit(endpoint uint8, data []byte, timeout time.Duration) (pending, error) {
+	ep := t.match.out()
+	if endpoint == endpointIN {
+		ep = t.match.in()
+	}
+	p := &usbfsPending{t: t, data: data}
+	p.urb = usbfsURB{
+		typ:          usbfsURBTypeBulk,
+		endpoint:     ep,
+		buffer:       unsafe.Pointer(&data[0]),
+		bufferLength: int32(len(data)),
+	}
+	if err := ioctl(t.f, usbfsSubmitURB, unsafe.Pointer(&p.urb)); err != nil {
+		return nil, err
+	}
+	p.timer = time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&p.timedOut, 1)
+		p.discard()
+	})
+	return p, nil
+}
+
+func (p *usbfsPending) discard() {
+	ioctl(p.t.f, usbfsDiscardURB, unsafe.Pointer(&p.urb)) // fails once reaped
+}
+
+func (p *usbfsPending) wait() (int, error) {
+	defer p.timer.Stop()
+
+	if err := p.t.reap(&p.urb); err != nil {
+		return 0, err
+	}
+	switch errno := syscall.Errno(-p.urb.status); errno {
+	case 0:
+		return int(p.urb.actualLength), nil
+	case syscall.ENOENT, syscall.ECONNRESET: // discarded
+		if atomic.LoadInt32(&p.timedOut) != 0 {
+			errno = syscall.ETIMEDOUT
+		} else {
+			errno = syscall.EINTR
+		}
+		return int(p.urb.actualLength), usbfsError(errno)
+	default:
+		return int(p.urb.actualLength), usbfsError(errno)
+	}
+}
+
+func (p *usbfsPending) cancel() int {
+	p.discard()
+	n, _ := p.wait()
+	return n
+}
+
+// reap waits for the given URB to complete. URBs reaped while waiting are
+// recorded so that they may be found by their own waiters.
+func (t *usbfsTransport) reap(urb *usbfsURB) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for !t.reaped[urb] {
+		var reaped *usbfsURB
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, t.f.Fd(), usbfsReapURB, uintptr(unsafe.Pointer(&reaped)))
+		if errno == syscall.EINTR {
+			continue
+		} else if errno != 0 {
+			return usbfsError(errno)
+		}
+		if reaped == urb {
+			return nil
+		}
+		if t.reaped == nil {
+			t.reaped = make(map[*usbfsURB]bool)
+		}
+		t.reaped[reaped] = true
+	}
+	delete(t.reaped, urb)
+	return nil
+}
+
+func (t *usbfsTransport) MaxPacketSize() int { return t.packetSize }
+
+func (t *usbfsTransport) Reset() error {
+	return ioctl(t.f, usbfsReset, nil)
+}
+
+func (t *usbfsTransport) Close() error {
+	if t.f == nil {
+		return nil
+	}
+	defer func() {
+		t.f.Close()
+		t.f = nil
+	}()
+
+	iface := uint32(t.match.Interface)
+	return ioctl(t.f, usbfsReleaseInterface, unsafe.Pointer(&iface))
+}
+
+func ioctl(f *os.File, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(arg)); errno != 0 {
+		return usbfsError(errno)
+	}
+	return nil
+}
+
+// usbfsCodes maps errno values to libusb error codes as libusb does on Linux.
+var usbfsCodes = map[syscall.Errno]int{
+	syscall.EIO:       codeIO,
+	syscall.EINVAL:    codeInvalidParam,
+	syscall.EACCES:    codeAccess,
+	syscall.EPERM:     codeAccess,
+	syscall.ENODEV:    codeNoDevice,
+	syscall.ESHUTDOWN: codeNoDevice,
+	syscall.ENOENT:    codeNotFound,
+	syscall.EBUSY:     codeBusy,
+	syscall.ETIMEDOUT: codeTimeout,
+	syscall.EOVERFLOW: codeOverflow,
+	syscall.EPIPE:     codePipe,
+	syscall.EINTR:     codeInterrupted,
+	syscall.ENOMEM:    codeNoMem,
+	syscall.ENOSYS:    codeNotSupported,
+}
+
+func usbfsError(errno syscall.Errno) error {
+	code, ok := usbfsCodes[errno]
+	if !ok {
+		code = codeOther
+	}
+	return &USBError{Code: code, Msg: errno.Error()}
+}
+
+// sysfsAttr returns the value of the named attribute of a sysfs directory.
+func sysfsAttr(dir, name string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// sysfsInt returns the value of the named attribute of a sysfs directory
+// parsed as an integer in the given base.
+func sysfsInt(dir, name string, base int) (int, error) {
+	s, err := sysfsAttr(dir, name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(s, base, 32)
+	return int(n), err
+}
+
+// devices implements the bus interface by reading the device attributes
+// found in sysfs.
+func (usbSession) devices(fn func(vendor, product uint16, transport func(Match) Transport) error) error {
+	entries, err := ioutil.ReadDir(sysfsRoot)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if strings.ContainsRune(entry.Name(), ':') {
+			continue // interface
+		}
+		dir := filepath.Join(sysfsRoot, entry.Name())
+
+		vendor, err := sysfsInt(dir, "idVendor", 16)
+		if err != nil {
+			continue
+		}
+		product, err := sysfsInt(dir, "idProduct", 16)
+		if err != nil {
+			continue
+		}
+		bus, err := sysfsInt(dir, "busnum", 10)
+		if err != nil {
+			continue
+		}
+		addr, err := sysfsInt(dir, "devnum", 10)
+		if err != nil {
+			continue
+		}
+		err = fn(uint16(vendor), uint16(product), func(m Match) Transport {
+			t := &usbfsTransport{match: m, dir: dir, bus: bus, addr: addr}
+
+			// A device whose endpoint cannot be found is reported
+			// when it is opened rather than failing the
+			// enumeration.
+			ep, err := t.endpoint(m.out())
+			if err == nil {
+				t.packetSize, err = sysfsInt(ep, "wMaxPacketSize", 16)
+			}
+			t.err = err
+			return t
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usbSession is empty; usbfs requires no initialization.
+type usbSession struct{}
+
+func (s *Session) init() error { return nil }
+
+// SetDebug is a no-op; the usbfs backend does not log messages.
+func (s *Session) SetDebug(level DebugLevel) {}
+
+// Close is a no-op; the usbfs backend holds no resources between
+// enumerations.
+func (s *Session) Close() error { return nil }
+
+// watchHotplug is not supported by the usbfs backend; devices are instead
+// periodically enumerated.
+func (s *Session) watchHotplug(ctx context.Context) (<-chan Event, error) {
+	return nil, errNoHotplug
+}
@@ -0,0 +1,230 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+//go:build usbfs || !cgo
+// +build usbfs !cgo
+
+package eeprom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// usbfsDevices returns the transports of the devices found by Walk.
+func usbfsDevices(t *testing.T, s *Session) []*usbfsTransport {
+	var devices []*usbfsTransport
+
+	err := s.Walk(func(d *Device) error {
+		devices = append(devices, d.t.(*usbfsTransport))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return devices
+}
+
+// sysfsTree creates files with the given contents in a temporary directory,
+// which is used as the sysfs root until the test completes.
+func sysfsTree(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "sysfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := sysfsRoot
+	t.Cleanup(func() { sysfsRoot = saved })
+	sysfsRoot = root
+	return root
+}
+
+func TestUsbfsDevices(t *testing.T) {
+	sysfsTree(t, map[string]string{
+		"usb1/idVendor":                    "1d6b",
+		"usb1/idProduct":                   "0002",
+		"usb1/busnum":                      "1",
+		"usb1/devnum":                      "1",
+		"1-1/idVendor":                     "04d8",
+		"1-1/idProduct":                    "f4cd",
+		"1-1/busnum":                       "1",
+		"1-1/devnum":                       "5",
+		"1-1/bcdDevice":                    "0102",
+		"1-1/speed":                        "12",
+		"1-1/manufacturer":                 "Steven Stallion",
+		"1-1/product":                      "USB EEPROM Programmer",
+		"1-1/serial":                       "A1B2C3",
+		"1-1/1-1:1.0/ep_01/wMaxPacketSize": "0040",
+		"1-1/1-1:1.0/ep_81/wMaxPacketSize": "0040",
+		"1-1:1.0/bInterfaceNumber":         "00",
+		"1-2/idVendor":                     "046d",
+		"1-2/idProduct":                    "c52b",
+		"1-2/busnum":                       "1",
+		"1-2/devnum":                       "7",
+	})
+
+	devices := usbfsDevices(t, &Session{bus: usbSession{}})
+	if len(devices) != 1 {
+		t.Fatalf("expected 1 device; got %d", len(devices))
+	}
+	d := devices[0]
+	if id := d.ID(); id != "1:5" {
+		t.Errorf("expected ID 1:5; got %s", id)
+	}
+	if path := d.path(); path != filepath.Join(devfsRoot, "001/005") {
+		t.Errorf("unexpected path %s", path)
+	}
+	if n := d.MaxPacketSize(); n != 64 {
+		t.Errorf("expected max packet size 64; got %d", n)
+	}
+
+	info, err := d.info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeviceInfo{
+		ID:            "1:5",
+		Bus:           1,
+		Address:       5,
+		PortPath:      "1-1",
+		Speed:         SpeedFull,
+		Manufacturer:  "Steven Stallion",
+		Product:       "USB EEPROM Programmer",
+		SerialNumber:  "A1B2C3",
+		Version:       0x0102,
+		InPacketSize:  64,
+		OutPacketSize: 64,
+	}
+	if info != want {
+		t.Errorf("expected %+v; got %+v", want, info)
+	}
+	for _, id := range []string{"1:5", "1-1", "A1B2C3"} {
+		if !info.Matches(id) {
+			t.Errorf("expected %s to match", id)
+		}
+	}
+}
+
+func TestUsbfsDevicesMissingEndpoint(t *testing.T) {
+	sysfsTree(t, map[string]string{
+		"1-1/idVendor":                     "04d8",
+		"1-1/idProduct":                    "f4cd",
+		"1-1/busnum":                       "1",
+		"1-1/devnum":                       "5",
+		"1-2/idVendor":                     "04d8",
+		"1-2/idProduct":                    "f4cd",
+		"1-2/busnum":                       "1",
+		"1-2/devnum":                       "7",
+		"1-2/1-2:1.0/ep_01/wMaxPacketSize": "0040",
+		"1-2/1-2:1.0/ep_81/wMaxPacketSize": "0040",
+	})
+
+	devices := usbfsDevices(t, &Session{bus: usbSession{}})
+	// The device is still enumerated, but fails to open.
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices; got %d", len(devices))
+	}
+	if err := devices[0].open(); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := devices[1].MaxPacketSize(); n != 64 {
+		t.Errorf("expected max packet size 64; got %d", n)
+	}
+}
+
+func TestUsbfsDevicesMatch(t *testing.T) {
+	sysfsTree(t, map[string]string{
+		"1-1/idVendor":                     "04d8",
+		"1-1/idProduct":                    "f4cd",
+		"1-1/busnum":                       "1",
+		"1-1/devnum":                       "5",
+		"1-1/1-1:1.0/ep_01/wMaxPacketSize": "0040",
+		"1-1/1-1:1.0/ep_81/wMaxPacketSize": "0040",
+		"1-2/idVendor":                     "1209",
+		"1-2/idProduct":                    "0001",
+		"1-2/busnum":                       "1",
+		"1-2/devnum":                       "7",
+		"1-2/1-2:1.1/ep_02/wMaxPacketSize": "0200",
+		"1-2/1-2:1.1/ep_82/wMaxPacketSize": "0200",
+	})
+
+	s := &Session{bus: usbSession{}}
+	s.SetMatches(Match{Vendor: 0x1209, Product: 0x0001, Interface: 1, Endpoint: 2})
+
+	devices := usbfsDevices(t, s)
+	if len(devices) != 1 {
+		t.Fatalf("expected 1 device; got %d", len(devices))
+	}
+	d := devices[0]
+	if id := d.ID(); id != "1:7" {
+		t.Errorf("expected ID 1:7; got %s", id)
+	}
+	if n := d.MaxPacketSize(); n != 512 {
+		t.Errorf("expected max packet size 512; got %d", n)
+	}
+}
+
+func TestList(t *testing.T) {
+	root := sysfsTree(t, map[string]string{
+		"1-1/idVendor":                     "04d8",
+		"1-1/idProduct":                    "f4cd",
+		"1-1/busnum":                       "1",
+		"1-1/devnum":                       "5",
+		"1-1/1-1:1.0/ep_01/wMaxPacketSize": "0040",
+		"1-1/1-1:1.0/ep_81/wMaxPacketSize": "0040",
+	})
+
+	devices, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("expected 1 device; got %d", len(devices))
+	}
+	if id := devices[0].ID(); id != "1:5" {
+		t.Errorf("expected ID 1:5; got %s", id)
+	}
+	// Devices that were never opened may be closed.
+	if err := devices[0].Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sysfsRoot = filepath.Join(root, "empty")
+	os.Mkdir(sysfsRoot, 0755)
+	if devices, err := List(); err != nil || len(devices) != 0 {
+		t.Fatalf("expected no devices; got %v, %v", devices, err)
+	}
+}
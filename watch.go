@@ -0,0 +1,146 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+// Event types reported by Watch.
+const (
+	DeviceArrived EventType = iota + 1
+	DeviceLeft
+)
+
+func (t EventType) String() string {
+	switch t {
+	case DeviceArrived:
+		return "arrived"
+	case DeviceLeft:
+		return "left"
+	}
+	return "unknown"
+}
+
+// Event describes the arrival or removal of a supported device. ID matches
+// the value returned by Device.ID, and may be used to locate the device with
+// Walk once it has arrived.
+type Event struct {
+	Type EventType
+	ID   string
+}
+
+// pollInterval is the interval at which devices are enumerated when hotplug
+// notifications are not available.
+var pollInterval = time.Second
+
+var errNoHotplug = errors.New("hotplug not supported")
+
+// Watch reports the arrival and removal of supported devices on the returned
+// channel until the given context is done, at which point the channel is
+// closed. Devices attached when Watch is called are reported as arrivals.
+//
+// Hotplug notifications are used if supported by the host; otherwise devices
+// are periodically enumerated.
+func (s *Session) Watch(ctx context.Context) (<-chan Event, error) {
+	ch, err := s.watchHotplug(ctx)
+	if err != errNoHotplug {
+		return ch, err
+	}
+	return watchPoll(ctx, pollInterval, s.listIDs), nil
+}
+
+// Watch calls Watch on the default session.
+func Watch(ctx context.Context) (<-chan Event, error) {
+	s, err := DefaultSession()
+	if err != nil {
+		return nil, err
+	}
+	return s.Watch(ctx)
+}
+
+// listIDs returns the IDs of supported devices attached to the host.
+func (s *Session) listIDs() ([]string, error) {
+	var ids []string
+
+	err := s.Walk(func(d *Device) error {
+		ids = append(ids, d.ID())
+		return nil
+	})
+	if err == ErrNoDevices {
+		err = nil
+	}
+	return ids, err
+}
+
+// watchPoll reports changes to the set of IDs returned by list, which is
+// called at the given interval. Enumeration errors are ignored.
+func watchPoll(ctx context.Context, interval time.Duration, list func() ([]string, error)) <-chan Event {
+	ch := make(chan Event)
+
+	go func() {
+		defer close(ch)
+
+		seen := make(map[string]bool)
+		for {
+			if ids, err := list(); err == nil {
+				current := make(map[string]bool, len(ids))
+				for _, id := range ids {
+					current[id] = true
+				}
+				var events []Event
+				for _, id := range ids {
+					if !seen[id] {
+						events = append(events, Event{DeviceArrived, id})
+					}
+				}
+				for id := range seen {
+					if !current[id] {
+						events = append(events, Event{DeviceLeft, id})
+					}
+				}
+				for _, e := range events {
+					select {
+					case ch <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+				seen = current
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2014 Steven Stallion <sstallion@gmail.com>
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+// 1. Redistributions of source code must retain the above copyright
+//    notice, this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright
+//    notice, this list of conditions and the following disclaimer in the
+//    documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR AND CONTRIBUTORS ``AS IS'' AND
+// ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED.  IN NO EVENT SHALL THE AUTHOR OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS
+// OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION)
+// HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY
+// OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// SUCH DAMAGE.
+
+package eeprom
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchPoll(t *testing.T) {
+	var mu sync.Mutex
+	steps := [][]string{
+		{"1:5"},
+		{"1:5", "1:6"},
+		{"1:6"},
+	}
+	list := func() ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ids := steps[0]
+		if len(steps) > 1 {
+			steps = steps[1:]
+		}
+		return ids, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := watchPoll(ctx, time.Millisecond, list)
+
+	var events []Event
+	for e := range ch {
+		if events = append(events, e); len(events) == 3 {
+			cancel()
+		}
+	}
+	want := []Event{
+		{DeviceArrived, "1:5"},
+		{DeviceArrived, "1:6"},
+		{DeviceLeft, "1:5"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("expected %v; got %v", want, events)
+	}
+}